@@ -0,0 +1,121 @@
+package eventsource
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CacheStats reports the effectiveness of a Cache
+type CacheStats struct {
+	// Hits is the number of loads that found the aggregate in the cache
+	Hits int64
+
+	// Misses is the number of loads that did not find the aggregate in the cache
+	Misses int64
+
+	// Evictions is the number of aggregates removed to make room for others
+	Evictions int64
+
+	// Invalidations is the number of aggregates removed due to conflicting saves or via Invalidate
+	Invalidations int64
+
+	// Len is the number of aggregates currently held
+	Len int
+}
+
+// Cache holds the most recently used aggregates of a repository, along with the version of each, so Load only needs
+// to fetch and apply events saved since the aggregate was cached.  Aggregates are held in their encoded form, using
+// Snapshotter, so callers never share an instance; see WithCache.  A Cache must not be shared between repositories.
+type Cache struct {
+	mux     *sync.Mutex
+	size    int
+	lru     *list.List
+	entries map[string]*list.Element
+	stats   CacheStats
+}
+
+type cacheEntry struct {
+	aggregateID string
+	version     int
+	data        []byte
+}
+
+// NewCache returns a Cache that holds up to size aggregates
+func NewCache(size int) *Cache {
+	if size < 1 {
+		size = 1
+	}
+
+	return &Cache{
+		mux:     &sync.Mutex{},
+		size:    size,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// Stats returns the statistics of the Cache
+func (c *Cache) Stats() CacheStats {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	stats := c.stats
+	stats.Len = c.lru.Len()
+	return stats
+}
+
+// Invalidate removes the aggregate from the Cache
+func (c *Cache) Invalidate(aggregateID string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if element, ok := c.entries[aggregateID]; ok {
+		c.lru.Remove(element)
+		delete(c.entries, aggregateID)
+		c.stats.Invalidations++
+	}
+}
+
+func (c *Cache) get(aggregateID string) (cacheEntry, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	element, ok := c.entries[aggregateID]
+	if !ok {
+		c.stats.Misses++
+		return cacheEntry{}, false
+	}
+
+	c.stats.Hits++
+	c.lru.MoveToFront(element)
+	return *element.Value.(*cacheEntry), true
+}
+
+// put caches the encoded aggregate unless a newer version is already held
+func (c *Cache) put(aggregateID string, version int, data []byte) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if element, ok := c.entries[aggregateID]; ok {
+		entry := element.Value.(*cacheEntry)
+		if entry.version <= version {
+			entry.version = version
+			entry.data = data
+		}
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	c.entries[aggregateID] = c.lru.PushFront(&cacheEntry{
+		aggregateID: aggregateID,
+		version:     version,
+		data:        data,
+	})
+
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).aggregateID)
+		c.stats.Evictions++
+	}
+}
@@ -0,0 +1,176 @@
+package eventsource_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/savaki/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+// fetchCounter records the versions passed to FetchAfter
+type fetchCounter struct {
+	*eventsource.MemoryStore
+	fetches []int
+	after   []int
+}
+
+func (f *fetchCounter) Fetch(ctx context.Context, aggregateID string, version int) (eventsource.History, error) {
+	f.fetches = append(f.fetches, version)
+	return f.MemoryStore.Fetch(ctx, aggregateID, version)
+}
+
+func (f *fetchCounter) FetchAfter(ctx context.Context, aggregateID string, version int) (eventsource.History, error) {
+	f.after = append(f.after, version)
+	return f.MemoryStore.FetchAfter(ctx, aggregateID, version)
+}
+
+// Tally counts renames in an unexported field
+type Tally struct {
+	Entity
+	renames int
+}
+
+func (t *Tally) On(event eventsource.Event) bool {
+	if _, ok := event.(*EntityNameSet); ok {
+		t.renames++
+	}
+	return t.Entity.On(event)
+}
+
+// CachedTally is a Tally that implements Snapshotter, encoding its unexported field
+type CachedTally struct {
+	Tally
+}
+
+type tallySnapshot struct {
+	Entity  Entity
+	Renames int
+}
+
+func (c *CachedTally) MarshalSnapshot() ([]byte, error) {
+	return json.Marshal(tallySnapshot{Entity: c.Entity, Renames: c.renames})
+}
+
+func (c *CachedTally) UnmarshalSnapshot(data []byte) error {
+	var snapshot tallySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	c.Entity, c.renames = snapshot.Entity, snapshot.Renames
+	return nil
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	store := &fetchCounter{MemoryStore: eventsource.NewMemoryStore()}
+	cache := eventsource.NewCache(10)
+	registry := eventsource.New(&SnapshotEntity{}, eventsource.WithStore(store), eventsource.WithCache(cache))
+	registry.Bind(EntityCreated{}, EntityNameSet{})
+
+	err := registry.Save(ctx,
+		&EntityCreated{Model: eventsource.Model{ID: id, Version: 1}},
+		&EntityNameSet{Model: eventsource.Model{ID: id, Version: 2}, Name: "Jones"},
+	)
+	assert.Nil(t, err)
+
+	v, err := registry.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Jones", v.(*SnapshotEntity).Name)
+	assert.Equal(t, eventsource.CacheStats{Misses: 1, Len: 1}, cache.Stats())
+
+	// Test - Changes made by the caller do not affect the cached aggregate
+
+	v.(*SnapshotEntity).Name = "changed"
+
+	v, err = registry.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Jones", v.(*SnapshotEntity).Name)
+	assert.Equal(t, eventsource.CacheStats{Hits: 1, Misses: 1, Len: 1}, cache.Stats())
+
+	// Test - Only events newer than the cached version are fetched
+
+	err = registry.Save(ctx, &EntityNameSet{Model: eventsource.Model{ID: id, Version: 3}, Name: "Sarah"})
+	assert.Nil(t, err)
+
+	v, err = registry.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Sarah", v.(*SnapshotEntity).Name)
+	assert.Equal(t, 3, v.(*SnapshotEntity).Version)
+	assert.Equal(t, []int{0}, store.fetches)
+	assert.Equal(t, []int{2, 2}, store.after)
+
+	v, err = registry.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 2, 3}, store.after)
+
+	// Test - A conflicting save invalidates the cached aggregate
+
+	err = registry.Save(ctx, &EntityNameSet{Model: eventsource.Model{ID: id, Version: 3}, Name: "Jane"})
+	assert.NotNil(t, err)
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Invalidations)
+	assert.Equal(t, 0, stats.Len)
+
+	v, err = registry.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Sarah", v.(*SnapshotEntity).Name)
+	assert.Equal(t, []int{0, 0}, store.fetches)
+}
+
+func TestCacheEviction(t *testing.T) {
+	ctx := context.Background()
+
+	cache := eventsource.NewCache(1)
+	registry := eventsource.New(&SnapshotEntity{}, eventsource.WithCache(cache))
+	registry.Bind(EntityCreated{})
+
+	err := registry.Save(ctx, &EntityCreated{Model: eventsource.Model{ID: "a", Version: 1}})
+	assert.Nil(t, err)
+	err = registry.Save(ctx, &EntityCreated{Model: eventsource.Model{ID: "b", Version: 1}})
+	assert.Nil(t, err)
+
+	for _, id := range []string{"a", "b", "a"} {
+		v, err := registry.Load(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, id, v.(*SnapshotEntity).ID)
+	}
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(0), stats.Hits)
+	assert.Equal(t, int64(3), stats.Misses)
+	assert.Equal(t, int64(2), stats.Evictions)
+	assert.Equal(t, 1, stats.Len)
+}
+
+func TestCacheUnexported(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	// Test - Aggregates that do not implement Snapshotter are rejected rather than cached as json
+
+	assert.Panics(t, func() { eventsource.New(&Tally{}, eventsource.WithCache(eventsource.NewCache(10))) })
+
+	// Test - Unexported fields survive the cache
+
+	cache := eventsource.NewCache(10)
+	registry := eventsource.New(&CachedTally{}, eventsource.WithCache(cache))
+	registry.Bind(EntityCreated{}, EntityNameSet{})
+
+	err := registry.Save(ctx,
+		&EntityCreated{Model: eventsource.Model{ID: id, Version: 1}},
+		&EntityNameSet{Model: eventsource.Model{ID: id, Version: 2}, Name: "Jones"},
+	)
+	assert.Nil(t, err)
+
+	for i := 0; i < 2; i++ {
+		v, err := registry.Load(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, v.(*CachedTally).renames)
+	}
+	assert.Equal(t, eventsource.CacheStats{Hits: 1, Misses: 1, Len: 1}, cache.Stats())
+}
@@ -2,7 +2,9 @@ package command
 
 import (
 	"context"
+	"errors"
 	"reflect"
+	"time"
 
 	"github.com/savaki/eventsource"
 )
@@ -13,6 +15,21 @@ const (
 	CodeAggregateNotCommandHandler = "AggregateNotCommandHandler"
 	CodeHandlerErr                 = "HandlerErr"
 	CodeSaveErr                    = "SaveErr"
+	CodeAggregateNotSaga           = "AggregateNotSaga"
+	CodeDispatchErr                = "DispatchErr"
+	CodeRetriesExhaustedErr        = "RetriesExhaustedErr"
+)
+
+// Sentinel errors, one for each code, for use with errors.Is
+var (
+	ErrPreprocessor               = eventsource.NewError(nil, CodePreprocessorErr, "preprocessor failed")
+	ErrEventLoad                  = eventsource.NewError(nil, CodeEventLoadErr, "unable to load aggregate")
+	ErrAggregateNotCommandHandler = eventsource.NewError(nil, CodeAggregateNotCommandHandler, "aggregate not command handler")
+	ErrHandler                    = eventsource.NewError(nil, CodeHandlerErr, "handler failed")
+	ErrSave                       = eventsource.NewError(nil, CodeSaveErr, "unable to save events")
+	ErrAggregateNotSaga           = eventsource.NewError(nil, CodeAggregateNotSaga, "aggregate not saga")
+	ErrDispatch                   = eventsource.NewError(nil, CodeDispatchErr, "unable to dispatch command")
+	ErrRetriesExhausted           = eventsource.NewError(nil, CodeRetriesExhaustedErr, "retries exhausted")
 )
 
 // Constructor is an interface that a Command may implement to indicate the Command is the "constructor"
@@ -32,52 +49,134 @@ type Dispatcher interface {
 	Dispatch(ctx context.Context, cmd Interface) error
 }
 
-type dispatchFunc func(ctx context.Context, cmd Interface) error
+// Option provides optional configuration to the Dispatcher returned by NewDispatcher
+type Option func(*dispatcher)
+
+// WithPreprocessors specifies Preprocessors to be executed, in order, prior to each command being applied
+func WithPreprocessors(preprocessors ...Preprocessor) Option {
+	return func(d *dispatcher) {
+		d.preprocessors = append(d.preprocessors, preprocessors...)
+	}
+}
+
+// WithMiddleware specifies Middleware that wraps the execution of each command; the first Middleware provided is the
+// outermost
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(d *dispatcher) {
+		d.middleware = append(d.middleware, middleware...)
+	}
+}
+
+type dispatcher struct {
+	repo          eventsource.Repository
+	preprocessors []Preprocessor
+	middleware    []Middleware
+	dispatch      DispatchFunc
 
-func (fn dispatchFunc) Dispatch(ctx context.Context, cmd Interface) error {
-	return fn(ctx, cmd)
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
 }
 
-// New instantiates a new Dispatcher using the Repository and optional Preprocessors provided
+// New instantiates a new Dispatcher using the Repository and optional Preprocessors provided.  Events returned by the
+// Handler are saved against the version of the loaded aggregate, or version 0 for Constructor commands, using
+// Repository.SaveVersion; if the aggregate was modified concurrently, the CodeSaveErr returned has a cause with the
+// eventsource.DuplicateVersion code.  Errors wrap their causes, so
+// errors.Is(err, eventsource.ErrDuplicateVersion) and errors.Is(err, eventsource.ErrAggregateNotFound) may be used to
+// detect conflicts and missing aggregates.
 func New(repo eventsource.Repository, preprocessors ...Preprocessor) Dispatcher {
-	return dispatchFunc(func(ctx context.Context, cmd Interface) error {
-		for _, p := range preprocessors {
-			err := p.Before(ctx, cmd)
-			if err != nil {
-				return eventsource.NewError(err, CodePreprocessorErr, "processor failed on command, %#v", cmd)
-			}
+	return NewDispatcher(repo, WithPreprocessors(preprocessors...))
+}
+
+// NewDispatcher instantiates a new Dispatcher using the Repository and Options provided; see New
+func NewDispatcher(repo eventsource.Repository, opts ...Option) Dispatcher {
+	d := &dispatcher{
+		repo:       repo,
+		backoff:    DefaultBackoff,
+		maxBackoff: DefaultMaxBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	d.dispatch = d.apply
+	for i := len(d.middleware) - 1; i >= 0; i-- {
+		d.dispatch = d.middleware[i](d.dispatch)
+	}
+
+	return d
+}
+
+// Dispatch implements the Dispatcher interface
+func (d *dispatcher) Dispatch(ctx context.Context, cmd Interface) error {
+	_, err := d.dispatch(ctx, cmd)
+	return err
+}
+
+// apply executes the preprocessors and then the command, retrying the command on conflict if configured to do so; see
+// WithRetry.  The events returned by the Handler are returned even if they could not be saved.
+func (d *dispatcher) apply(ctx context.Context, cmd Interface) ([]eventsource.Event, error) {
+	for _, p := range d.preprocessors {
+		err := p.Before(ctx, cmd)
+		if err != nil {
+			return nil, eventsource.NewError(err, CodePreprocessorErr, "processor failed on command, %#v", cmd)
 		}
+	}
 
-		var aggregate eventsource.Aggregate
-		if v, ok := cmd.(Constructor); ok && v.New() {
-			aggregate = repo.New()
-
-		} else {
-			aggregateID := cmd.AggregateID()
-			v, err := repo.Load(ctx, aggregateID)
-			if err != nil {
-				return eventsource.NewError(err, CodeEventLoadErr, "Unable to load %v [%v]", typeOf(repo.New()), aggregateID)
-			}
-			aggregate = v
+	retries := d.retries
+	if v, ok := cmd.(Constructor); ok && v.New() {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		events, err := d.execute(ctx, cmd)
+		if err == nil || retries == 0 || !errors.Is(err, eventsource.ErrDuplicateVersion) {
+			return events, err
 		}
 
-		handler, ok := aggregate.(Handler)
-		if !ok {
-			return eventsource.NewError(nil, CodeAggregateNotCommandHandler, "%#v does not implement command.Handler", typeOf(aggregate))
+		if attempt >= retries {
+			return events, eventsource.NewError(err, CodeRetriesExhaustedErr, "Failed to apply command, %v, after %v retries", typeOf(cmd), retries)
 		}
 
-		events, err := handler.Apply(ctx, cmd)
-		if err != nil {
-			return eventsource.NewError(err, CodeHandlerErr, "Failed to apply command, %v, to aggregate, %v", typeOf(cmd), typeOf(aggregate))
+		if err := sleep(ctx, d.delay(attempt)); err != nil {
+			return events, err
 		}
+	}
+}
+
+// execute loads the aggregate, applies the command, and saves the resulting events
+func (d *dispatcher) execute(ctx context.Context, cmd Interface) ([]eventsource.Event, error) {
+	var aggregate eventsource.Aggregate
+	var version int
+	if v, ok := cmd.(Constructor); ok && v.New() {
+		aggregate = d.repo.New()
 
-		err = repo.Save(ctx, events...)
+	} else {
+		aggregateID := cmd.AggregateID()
+		v, loaded, err := d.repo.LoadWithVersion(ctx, aggregateID)
 		if err != nil {
-			return eventsource.NewError(err, CodeSaveErr, "Failed to save events for %v, %v", typeOf(aggregate), cmd.AggregateID())
+			return nil, eventsource.NewError(err, CodeEventLoadErr, "Unable to load %v [%v]", typeOf(d.repo.New()), aggregateID)
 		}
+		aggregate, version = v, loaded
+	}
+
+	handler, ok := aggregate.(Handler)
+	if !ok {
+		return nil, eventsource.NewError(nil, CodeAggregateNotCommandHandler, "%#v does not implement command.Handler", typeOf(aggregate))
+	}
+
+	events, err := handler.Apply(ctx, cmd)
+	if err != nil {
+		return nil, eventsource.NewError(err, CodeHandlerErr, "Failed to apply command, %v, to aggregate, %v", typeOf(cmd), typeOf(aggregate))
+	}
+
+	err = d.repo.SaveVersion(ctx, version, events...)
+	if err != nil {
+		return events, eventsource.NewError(err, CodeSaveErr, "Failed to save events for %v, %v", typeOf(aggregate), cmd.AggregateID())
+	}
 
-		return nil
-	})
+	return events, nil
 }
 
 func typeOf(aggregate interface{}) string {
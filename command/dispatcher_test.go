@@ -2,6 +2,7 @@ package command_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -123,3 +124,85 @@ func TestLifecycle(t *testing.T) {
 	assert.Equal(t, updatedEmail, user.Email)
 	assert.Equal(t, name, user.Name)
 }
+
+func TestConflict(t *testing.T) {
+	repo := eventsource.New(&User{})
+	repo.Bind(UserCreated{}, UserEmailChanged{})
+
+	ctx := context.Background()
+	id := "123"
+
+	dispatcher := command.New(repo)
+	err := dispatcher.Dispatch(ctx, CreateCommand{
+		Model: command.Model{ID: id},
+		Name:  "John Doe",
+	})
+	assert.Nil(t, err)
+
+	// Test - Recreating an existing aggregate conflicts with the stored version
+
+	err = dispatcher.Dispatch(ctx, CreateCommand{
+		Model: command.Model{ID: id},
+		Name:  "Jane Doe",
+	})
+	assert.NotNil(t, err)
+
+	v, ok := err.(eventsource.Error)
+	assert.True(t, ok)
+	assert.Equal(t, command.CodeSaveErr, v.Code())
+
+	cause, ok := v.Cause().(eventsource.Error)
+	assert.True(t, ok)
+	assert.Equal(t, eventsource.DuplicateVersion, cause.Code())
+
+	assert.True(t, errors.Is(err, command.ErrSave))
+	assert.True(t, errors.Is(err, eventsource.ErrDuplicateVersion))
+}
+
+func TestNotFound(t *testing.T) {
+	repo := eventsource.New(&User{})
+	repo.Bind(UserCreated{}, UserEmailChanged{})
+
+	dispatcher := command.New(repo)
+	err := dispatcher.Dispatch(context.Background(), ChangeEmailCommand{
+		Model: command.Model{ID: "123"},
+		Email: "jane.doe@example.com",
+	})
+	assert.True(t, errors.Is(err, command.ErrEventLoad))
+	assert.True(t, errors.Is(err, eventsource.ErrAggregateNotFound))
+	assert.False(t, errors.Is(err, eventsource.ErrDuplicateVersion))
+}
+
+// versionRecorder records the expected versions passed to SaveVersion
+type versionRecorder struct {
+	*eventsource.MemoryStore
+	versions []int
+}
+
+func (v *versionRecorder) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...eventsource.Record) error {
+	v.versions = append(v.versions, expectedVersion)
+	return v.MemoryStore.SaveVersion(ctx, aggregateID, expectedVersion, records...)
+}
+
+func TestExpectedVersion(t *testing.T) {
+	store := &versionRecorder{MemoryStore: eventsource.NewMemoryStore()}
+	repo := eventsource.New(&User{}, eventsource.WithStore(store))
+	repo.Bind(UserCreated{}, UserEmailChanged{})
+
+	ctx := context.Background()
+	dispatcher := command.New(repo)
+	err := dispatcher.Dispatch(ctx, CreateCommand{
+		Model: command.Model{ID: "123"},
+		Name:  "John Doe",
+	})
+	assert.Nil(t, err)
+
+	// Test - Events are saved against the version of the loaded aggregate
+
+	err = dispatcher.Dispatch(ctx, ChangeEmailCommand{
+		Model: command.Model{ID: "123"},
+		Email: "jane.doe@example.com",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 1}, store.versions)
+}
@@ -0,0 +1,40 @@
+package command
+
+import (
+	"context"
+
+	"github.com/savaki/eventsource"
+)
+
+// DispatchFunc executes a command, returning the events produced by the Handler along with any error.  Events are
+// returned even if they could not be saved, in which case the error has the CodeSaveErr code.
+type DispatchFunc func(ctx context.Context, cmd Interface) ([]eventsource.Event, error)
+
+// Middleware wraps the execution of a command, in the manner of http middleware.  Middleware may act before calling
+// next, e.g. to start a timer or transaction, and after, observing the resulting events and error.  The context
+// passed to next is used to load and save the aggregate.
+type Middleware func(next DispatchFunc) DispatchFunc
+
+// Before returns Middleware that calls fn before the command is executed; the command is not executed if fn returns an
+// error
+func Before(fn func(ctx context.Context, cmd Interface) error) Middleware {
+	return func(next DispatchFunc) DispatchFunc {
+		return func(ctx context.Context, cmd Interface) ([]eventsource.Event, error) {
+			if err := fn(ctx, cmd); err != nil {
+				return nil, err
+			}
+			return next(ctx, cmd)
+		}
+	}
+}
+
+// After returns Middleware that calls fn with the events and error resulting from the execution of the command
+func After(fn func(ctx context.Context, cmd Interface, events []eventsource.Event, err error)) Middleware {
+	return func(next DispatchFunc) DispatchFunc {
+		return func(ctx context.Context, cmd Interface) ([]eventsource.Event, error) {
+			events, err := next(ctx, cmd)
+			fn(ctx, cmd, events, err)
+			return events, err
+		}
+	}
+}
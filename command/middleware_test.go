@@ -0,0 +1,98 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/savaki/eventsource"
+	"github.com/savaki/eventsource/command"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	repo := eventsource.New(&User{})
+	repo.Bind(UserCreated{}, UserEmailChanged{})
+
+	var calls []string
+	around := func(name string) command.Middleware {
+		return func(next command.DispatchFunc) command.DispatchFunc {
+			return func(ctx context.Context, cmd command.Interface) ([]eventsource.Event, error) {
+				calls = append(calls, name+":before")
+				events, err := next(ctx, cmd)
+				calls = append(calls, name+":after")
+				return events, err
+			}
+		}
+	}
+
+	var observed []eventsource.Event
+	var observedErr error
+	after := command.After(func(ctx context.Context, cmd command.Interface, events []eventsource.Event, err error) {
+		observed, observedErr = events, err
+	})
+
+	dispatcher := command.NewDispatcher(repo,
+		command.WithMiddleware(around("outer"), around("inner"), after),
+	)
+
+	ctx := context.Background()
+	err := dispatcher.Dispatch(ctx, CreateCommand{
+		Model: command.Model{ID: "123"},
+		Name:  "John Doe",
+	})
+	assert.Nil(t, err)
+
+	// Test - The first Middleware is the outermost
+
+	assert.Equal(t, []string{"outer:before", "inner:before", "inner:after", "outer:after"}, calls)
+
+	// Test - Middleware observes the events saved
+
+	assert.Nil(t, observedErr)
+	assert.Len(t, observed, 1)
+	assert.Equal(t, "John Doe", observed[0].(UserCreated).Name)
+
+	// Test - Middleware observes the events and error when the save fails
+
+	err = dispatcher.Dispatch(ctx, CreateCommand{
+		Model: command.Model{ID: "123"},
+		Name:  "Jane Doe",
+	})
+	assert.True(t, errors.Is(err, command.ErrSave))
+	assert.Equal(t, err, observedErr)
+	assert.Len(t, observed, 1)
+	assert.Equal(t, "Jane Doe", observed[0].(UserCreated).Name)
+}
+
+func TestBefore(t *testing.T) {
+	repo := eventsource.New(&User{})
+	repo.Bind(UserCreated{}, UserEmailChanged{})
+
+	boom := errors.New("boom")
+	preprocessed := false
+	dispatcher := command.NewDispatcher(repo,
+		command.WithPreprocessors(preprocessorFunc(func(ctx context.Context, cmd command.Interface) error {
+			preprocessed = true
+			return nil
+		})),
+		command.WithMiddleware(command.Before(func(ctx context.Context, cmd command.Interface) error {
+			return boom
+		})),
+	)
+
+	// Test - Errors returned by Before prevent the command from being executed
+
+	err := dispatcher.Dispatch(context.Background(), CreateCommand{Model: command.Model{ID: "123"}})
+	assert.Equal(t, boom, err)
+	assert.False(t, preprocessed)
+
+	_, err = repo.Load(context.Background(), "123")
+	assert.True(t, errors.Is(err, eventsource.ErrAggregateNotFound))
+}
+
+type preprocessorFunc func(ctx context.Context, cmd command.Interface) error
+
+func (fn preprocessorFunc) Before(ctx context.Context, cmd command.Interface) error {
+	return fn(ctx, cmd)
+}
@@ -0,0 +1,70 @@
+package command
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+const (
+	// DefaultBackoff is the default delay prior to the first retry; see WithBackoff
+	DefaultBackoff = 10 * time.Millisecond
+
+	// DefaultMaxBackoff is the default upper bound of the delay between retries; see WithBackoff
+	DefaultMaxBackoff = time.Second
+)
+
+// WithRetry retries commands that fail to save because the aggregate was modified concurrently, i.e. whose error has
+// a cause with the eventsource.DuplicateVersion code, up to retries times.  Each retry reloads the aggregate and
+// applies the command again, so Handlers must be free of side effects beyond the events they return.  Once the
+// retries are exhausted, an error with CodeRetriesExhaustedErr, wrapping the last failure, is returned.  Commands
+// implementing Constructor are not retried, as recreating an existing aggregate can never succeed.  Negative values
+// are treated as 0.
+func WithRetry(retries int) Option {
+	return func(d *dispatcher) {
+		if retries < 0 {
+			retries = 0
+		}
+		d.retries = retries
+	}
+}
+
+// WithBackoff specifies the delay prior to the first retry, which doubles with each subsequent retry up to maxBackoff;
+// defaults to DefaultBackoff and DefaultMaxBackoff.  A random jitter is applied to each delay, so concurrent commands
+// retry at different times.
+func WithBackoff(backoff, maxBackoff time.Duration) Option {
+	return func(d *dispatcher) {
+		d.backoff = backoff
+		d.maxBackoff = maxBackoff
+	}
+}
+
+// delay returns the delay prior to the specified retry, starting from 0, chosen uniformly between half the backoff
+// and the full backoff
+func (d *dispatcher) delay(attempt int) time.Duration {
+	backoff := d.backoff
+	for i := 0; i < attempt && backoff < d.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > d.maxBackoff {
+		backoff = d.maxBackoff
+	}
+
+	if half := int64(backoff / 2); half > 0 {
+		return time.Duration(half + rand.Int63n(half+1))
+	}
+	return backoff
+}
+
+// sleep blocks for the duration provided; returns the error of ctx if it is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,166 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/savaki/eventsource"
+	"github.com/savaki/eventsource/command"
+	"github.com/stretchr/testify/assert"
+)
+
+// interferingStore saves a competing UserEmailChanged event prior to each of the next conflicts calls to SaveVersion,
+// simulating a concurrent writer
+type interferingStore struct {
+	*eventsource.MemoryStore
+	serializer eventsource.Serializer
+	conflicts  int
+	saves      int
+}
+
+func (s *interferingStore) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...eventsource.Record) error {
+	s.saves++
+	if s.conflicts > 0 {
+		s.conflicts--
+
+		record, err := s.serializer.Serialize(UserEmailChanged{
+			Model: eventsource.Model{ID: aggregateID, Version: expectedVersion + 1, At: time.Now()},
+			Email: "concurrent@example.com",
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.MemoryStore.SaveVersion(ctx, aggregateID, expectedVersion, record); err != nil {
+			return err
+		}
+	}
+
+	return s.MemoryStore.SaveVersion(ctx, aggregateID, expectedVersion, records...)
+}
+
+func newInterferingRepository(t *testing.T) (eventsource.Repository, *interferingStore) {
+	serializer := eventsource.JSONSerializer()
+	store := &interferingStore{
+		MemoryStore: eventsource.NewMemoryStore(),
+		serializer:  serializer,
+	}
+
+	repo := eventsource.New(&User{},
+		eventsource.WithStore(store),
+		eventsource.WithSerializer(serializer),
+	)
+	assert.Nil(t, repo.Bind(UserCreated{}, UserEmailChanged{}))
+
+	err := command.New(repo).Dispatch(context.Background(), CreateCommand{
+		Model: command.Model{ID: "123"},
+		Name:  "John Doe",
+	})
+	assert.Nil(t, err)
+	store.saves = 0
+
+	return repo, store
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	repo, store := newInterferingRepository(t)
+	store.conflicts = 2
+
+	dispatcher := command.NewDispatcher(repo,
+		command.WithRetry(3),
+		command.WithBackoff(time.Millisecond, 2*time.Millisecond),
+	)
+
+	// Test - The command is reapplied to the reloaded aggregate until it saves
+
+	err := dispatcher.Dispatch(ctx, ChangeEmailCommand{
+		Model: command.Model{ID: "123"},
+		Email: "jane.doe@example.com",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, store.saves)
+
+	v, err := repo.Load(ctx, "123")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, v.(*User).Version)
+	assert.Equal(t, "jane.doe@example.com", v.(*User).Email)
+}
+
+func TestRetry_Exhausted(t *testing.T) {
+	ctx := context.Background()
+	repo, store := newInterferingRepository(t)
+	store.conflicts = 5
+
+	dispatcher := command.NewDispatcher(repo,
+		command.WithRetry(2),
+		command.WithBackoff(time.Millisecond, 2*time.Millisecond),
+	)
+
+	err := dispatcher.Dispatch(ctx, ChangeEmailCommand{
+		Model: command.Model{ID: "123"},
+		Email: "jane.doe@example.com",
+	})
+	assert.Equal(t, 3, store.saves)
+
+	v, ok := err.(eventsource.Error)
+	assert.True(t, ok)
+	assert.Equal(t, command.CodeRetriesExhaustedErr, v.Code())
+	assert.True(t, errors.Is(err, command.ErrRetriesExhausted))
+	assert.True(t, errors.Is(err, command.ErrSave))
+	assert.True(t, errors.Is(err, eventsource.ErrDuplicateVersion))
+
+	// Test - Without WithRetry, conflicts are returned immediately
+
+	store.saves = 0
+	err = command.NewDispatcher(repo).Dispatch(ctx, ChangeEmailCommand{
+		Model: command.Model{ID: "123"},
+		Email: "jane.doe@example.com",
+	})
+	assert.Equal(t, 1, store.saves)
+	assert.True(t, errors.Is(err, command.ErrSave))
+	assert.False(t, errors.Is(err, command.ErrRetriesExhausted))
+}
+
+func TestRetry_Negative(t *testing.T) {
+	ctx := context.Background()
+	repo, store := newInterferingRepository(t)
+	store.conflicts = 5
+
+	dispatcher := command.NewDispatcher(repo,
+		command.WithRetry(-1),
+		command.WithBackoff(time.Millisecond, 2*time.Millisecond),
+	)
+
+	// Test - Negative retries are treated as 0 rather than retrying forever
+
+	err := dispatcher.Dispatch(ctx, ChangeEmailCommand{
+		Model: command.Model{ID: "123"},
+		Email: "jane.doe@example.com",
+	})
+	assert.Equal(t, 1, store.saves)
+	assert.True(t, errors.Is(err, command.ErrSave))
+	assert.False(t, errors.Is(err, command.ErrRetriesExhausted))
+}
+
+func TestRetry_Context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	repo, store := newInterferingRepository(t)
+	store.conflicts = 5
+
+	dispatcher := command.NewDispatcher(repo,
+		command.WithRetry(3),
+		command.WithBackoff(time.Minute, time.Minute),
+	)
+
+	// Test - Backoff is abandoned once the context is done
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err := dispatcher.Dispatch(ctx, ChangeEmailCommand{
+		Model: command.Model{ID: "123"},
+		Email: "jane.doe@example.com",
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, store.saves)
+}
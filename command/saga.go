@@ -0,0 +1,121 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/savaki/eventsource"
+)
+
+// Saga is an event sourced Aggregate that coordinates a workflow spanning multiple aggregates, e.g. order, payment,
+// and shipment.  The state of each saga instance is persisted via a Repository like any other Aggregate.
+type Saga interface {
+	eventsource.Aggregate
+
+	// Handle reacts to an event, correlated to the saga instance with the id provided, by returning the events to be
+	// saved to the saga and the commands to be dispatched as a result.  Handle is called on a new instance, created by
+	// Repository.New, if no events have been saved for the saga.  position is the position of the event within the
+	// log, or 0 if unknown; a saga may save the position of the last event handled to ignore events delivered again.
+	Handle(ctx context.Context, sagaID string, event eventsource.Event, position int64) ([]eventsource.Event, []Interface, error)
+}
+
+// Correlator maps incoming events to the saga instances that handle them
+type Correlator interface {
+	// Correlate returns the id of the saga instance the event belongs to; ok is false if no saga handles the event
+	Correlate(event eventsource.Event) (sagaID string, ok bool)
+}
+
+// CorrelatorFunc provides a func implementation of Correlator
+type CorrelatorFunc func(event eventsource.Event) (string, bool)
+
+// Correlate implements the Correlator interface
+func (fn CorrelatorFunc) Correlate(event eventsource.Event) (string, bool) {
+	return fn(event)
+}
+
+// ProcessManager routes events to the saga instances they correlate to, dispatches the resulting commands, and saves
+// the events returned by the saga.  ProcessManager implements projection.Projector, allowing a projection.Runner to
+// feed it events from the log.
+//
+// Commands are delivered at least once: the saga events are only saved once every command has been dispatched, so an
+// event whose commands could not all be dispatched leaves the saga unchanged and may be handled again, e.g. when the
+// projection.Runner redelivers it.  Commands emitted by sagas must therefore be idempotent; the causation id attached
+// to each command identifies the event that caused it, and is the same each time the event is delivered.
+type ProcessManager struct {
+	repo       eventsource.Repository
+	dispatcher Dispatcher
+	correlator Correlator
+}
+
+// NewProcessManager returns a ProcessManager for the sagas held by repo, whose aggregate must implement Saga.
+// Commands emitted by the sagas are dispatched using dispatcher.
+func NewProcessManager(repo eventsource.Repository, dispatcher Dispatcher, correlator Correlator) *ProcessManager {
+	return &ProcessManager{
+		repo:       repo,
+		dispatcher: dispatcher,
+		correlator: correlator,
+	}
+}
+
+// Handle loads the saga instance the event correlates to, applies the event, and dispatches the resulting commands
+// before saving the resulting events.  If a command cannot be dispatched, an error with CodeDispatchErr is returned,
+// the remaining commands are not dispatched, and nothing is saved, so handling the event again dispatches every
+// command again.  An instance handling the same event concurrently may also dispatch the commands, but fails to save
+// with a CodeSaveErr, whose cause has the eventsource.DuplicateVersion code.
+//
+// The correlation id of the event, or the saga id if the event has none, is attached to the context, so the events
+// saved and the commands dispatched share the correlation id of the workflow.  The causation id attached is the id of
+// the aggregate of the event and its version, e.g. order-123:2.  position is passed to the Saga; see Saga.Handle.
+func (p *ProcessManager) Handle(ctx context.Context, event eventsource.Event, position int64) error {
+	sagaID, ok := p.correlator.Correlate(event)
+	if !ok {
+		return nil
+	}
+
+	correlationID := sagaID
+	if v, ok := event.(eventsource.MetadataProvider); ok {
+		if id := v.EventMetadata()[eventsource.MetadataCorrelationID]; id != "" {
+			correlationID = id
+		}
+	}
+	ctx = eventsource.ContextWithMetadata(ctx, map[string]string{
+		eventsource.MetadataCorrelationID: correlationID,
+		eventsource.MetadataCausationID:   fmt.Sprintf("%v:%v", event.AggregateID(), event.EventVersion()),
+	})
+
+	aggregate, version, err := p.repo.LoadWithVersion(ctx, sagaID)
+	if errors.Is(err, eventsource.ErrAggregateNotFound) {
+		aggregate, version, err = p.repo.New(), 0, nil
+	}
+	if err != nil {
+		return eventsource.NewError(err, CodeEventLoadErr, "Unable to load %v [%v]", typeOf(p.repo.New()), sagaID)
+	}
+
+	saga, ok := aggregate.(Saga)
+	if !ok {
+		return eventsource.NewError(nil, CodeAggregateNotSaga, "%#v does not implement command.Saga", typeOf(aggregate))
+	}
+
+	events, commands, err := saga.Handle(ctx, sagaID, event, position)
+	if err != nil {
+		return eventsource.NewError(err, CodeHandlerErr, "Failed to handle event, %v, with saga, %v", typeOf(event), typeOf(saga))
+	}
+
+	for _, cmd := range commands {
+		if err := p.dispatcher.Dispatch(ctx, cmd); err != nil {
+			return eventsource.NewError(err, CodeDispatchErr, "Failed to dispatch command, %v, from saga, %v, %v", typeOf(cmd), typeOf(saga), sagaID)
+		}
+	}
+
+	if err := p.repo.SaveVersion(ctx, version, events...); err != nil {
+		return eventsource.NewError(err, CodeSaveErr, "Failed to save events for %v, %v", typeOf(saga), sagaID)
+	}
+
+	return nil
+}
+
+// Project implements the projection.Projector interface
+func (p *ProcessManager) Project(ctx context.Context, event eventsource.Event, position int64) error {
+	return p.Handle(ctx, event, position)
+}
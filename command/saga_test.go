@@ -0,0 +1,298 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/savaki/eventsource"
+	"github.com/savaki/eventsource/command"
+	"github.com/savaki/eventsource/projection"
+	"github.com/stretchr/testify/assert"
+)
+
+// -- Events published by other aggregates --------------
+
+type OrderPlaced struct {
+	eventsource.ModelWithMetadata
+	Amount int
+}
+
+type PaymentReceived struct {
+	eventsource.Model
+	OrderID string
+}
+
+// -- Saga ----------------------------------------------
+
+type FulfillmentStarted struct {
+	eventsource.Model
+	OrderID  string
+	Position int64
+}
+
+type FulfillmentPaid struct {
+	eventsource.Model
+	Position int64
+}
+
+type RequestPayment struct {
+	command.Model
+	OrderID string
+	Amount  int
+}
+
+type ShipOrder struct {
+	command.Model
+}
+
+// Fulfillment coordinates the payment and shipment of an order
+type Fulfillment struct {
+	eventsource.Model
+	OrderID  string
+	Paid     bool
+	Position int64
+}
+
+func (f *Fulfillment) On(event eventsource.Event) bool {
+	switch v := event.(type) {
+	case *FulfillmentStarted:
+		f.OrderID = v.OrderID
+		f.Position = v.Position
+
+	case *FulfillmentPaid:
+		f.Paid = true
+		f.Position = v.Position
+
+	default:
+		return false
+	}
+
+	f.ID = event.AggregateID()
+	f.Version = event.EventVersion()
+	f.At = event.EventAt()
+
+	return true
+}
+
+func (f *Fulfillment) Handle(ctx context.Context, sagaID string, event eventsource.Event, position int64) ([]eventsource.Event, []command.Interface, error) {
+	if position > 0 && position <= f.Position {
+		return nil, nil, nil // delivered again
+	}
+
+	switch v := event.(type) {
+	case *OrderPlaced:
+		if f.Version > 0 {
+			return nil, nil, nil
+		}
+		return []eventsource.Event{
+			FulfillmentStarted{Model: eventsource.Model{ID: sagaID, Version: f.Version + 1, At: time.Now()}, OrderID: v.ID, Position: position},
+		}, []command.Interface{
+			RequestPayment{Model: command.Model{ID: "payment-" + v.ID}, OrderID: v.ID, Amount: v.Amount},
+		}, nil
+
+	case *PaymentReceived:
+		if f.Version == 0 || f.Paid {
+			return nil, nil, nil
+		}
+		return []eventsource.Event{
+			FulfillmentPaid{Model: eventsource.Model{ID: sagaID, Version: f.Version + 1, At: time.Now()}, Position: position},
+		}, []command.Interface{
+			ShipOrder{Model: command.Model{ID: f.OrderID}},
+		}, nil
+
+	default:
+		return nil, nil, errors.New("unhandled event")
+	}
+}
+
+var correlateFulfillment = command.CorrelatorFunc(func(event eventsource.Event) (string, bool) {
+	switch v := event.(type) {
+	case *OrderPlaced:
+		return "fulfillment-" + v.ID, true
+	case *PaymentReceived:
+		return "fulfillment-" + v.OrderID, true
+	default:
+		return "", false
+	}
+})
+
+// recorder is a Dispatcher that records the commands dispatched and fails while err is set
+type recorder struct {
+	mux      sync.Mutex
+	err      error
+	commands []command.Interface
+	metadata []map[string]string
+}
+
+func (r *recorder) Dispatch(ctx context.Context, cmd command.Interface) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+	r.commands = append(r.commands, cmd)
+	r.metadata = append(r.metadata, eventsource.MetadataFromContext(ctx))
+	return nil
+}
+
+func TestProcessManager(t *testing.T) {
+	ctx := context.Background()
+
+	store := eventsource.NewMemoryStore()
+	repo := eventsource.New(&Fulfillment{}, eventsource.WithStore(store))
+	repo.Bind(FulfillmentStarted{}, FulfillmentPaid{})
+
+	dispatcher := &recorder{}
+	pm := command.NewProcessManager(repo, dispatcher, correlateFulfillment)
+
+	placed := &OrderPlaced{
+		ModelWithMetadata: eventsource.ModelWithMetadata{
+			Model:    eventsource.Model{ID: "123", Version: 1},
+			Metadata: map[string]string{eventsource.MetadataCorrelationID: "abc"},
+		},
+		Amount: 100,
+	}
+	err := pm.Handle(ctx, placed, 0)
+	assert.Nil(t, err)
+
+	// Test - Events correlated to no saga are ignored
+
+	err = pm.Handle(ctx, &UserCreated{Model: eventsource.Model{ID: "123", Version: 1}}, 0)
+	assert.Nil(t, err)
+
+	// Test - Events for a saga that has not started save nothing
+
+	err = pm.Handle(ctx, &PaymentReceived{Model: eventsource.Model{ID: "payment-456", Version: 1}, OrderID: "456"}, 0)
+	assert.Nil(t, err)
+
+	_, err = repo.Load(ctx, "fulfillment-456")
+	assert.True(t, errors.Is(err, eventsource.ErrAggregateNotFound))
+
+	err = pm.Handle(ctx, &PaymentReceived{Model: eventsource.Model{ID: "payment-123", Version: 1}, OrderID: "123"}, 0)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []command.Interface{
+		RequestPayment{Model: command.Model{ID: "payment-123"}, OrderID: "123", Amount: 100},
+		ShipOrder{Model: command.Model{ID: "123"}},
+	}, dispatcher.commands)
+
+	v, err := repo.Load(ctx, "fulfillment-123")
+	assert.Nil(t, err)
+	assert.Equal(t, "123", v.(*Fulfillment).OrderID)
+	assert.True(t, v.(*Fulfillment).Paid)
+
+	// Test - The correlation id of the event is attached to saved events and dispatched commands
+
+	history, err := store.Fetch(ctx, "fulfillment-123", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", history[0].Metadata[eventsource.MetadataCorrelationID])
+	assert.Equal(t, "abc", dispatcher.metadata[0][eventsource.MetadataCorrelationID])
+	assert.Equal(t, "fulfillment-123", dispatcher.metadata[1][eventsource.MetadataCorrelationID])
+
+	// Test - The event handled is attached to saved events and dispatched commands as the causation id
+
+	assert.Equal(t, "123:1", history[0].Metadata[eventsource.MetadataCausationID])
+	assert.Equal(t, "123:1", dispatcher.metadata[0][eventsource.MetadataCausationID])
+	assert.Equal(t, "payment-123:1", dispatcher.metadata[1][eventsource.MetadataCausationID])
+}
+
+func TestProcessManager_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	repo := eventsource.New(&Fulfillment{})
+	repo.Bind(FulfillmentStarted{}, FulfillmentPaid{})
+
+	// Test - Dispatch failures are returned without saving the saga events
+
+	dispatcher := &recorder{}
+	pm := command.NewProcessManager(repo, dispatcher, correlateFulfillment)
+
+	placed := &OrderPlaced{ModelWithMetadata: eventsource.ModelWithMetadata{Model: eventsource.Model{ID: "123", Version: 1}}, Amount: 100}
+	assert.Nil(t, pm.Handle(ctx, placed, 1))
+
+	dispatcher.err = errors.New("boom")
+	paid := &PaymentReceived{Model: eventsource.Model{ID: "payment-123", Version: 1}, OrderID: "123"}
+	err := pm.Handle(ctx, paid, 2)
+	assert.True(t, errors.Is(err, command.ErrDispatch))
+	assert.True(t, errors.Is(err, dispatcher.err))
+
+	v, err := repo.Load(ctx, "fulfillment-123")
+	assert.Nil(t, err)
+	assert.False(t, v.(*Fulfillment).Paid)
+
+	// Test - Handling the event again delivers the commands that failed
+
+	dispatcher.err = nil
+	err = pm.Handle(ctx, paid, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []command.Interface{
+		RequestPayment{Model: command.Model{ID: "payment-123"}, OrderID: "123", Amount: 100},
+		ShipOrder{Model: command.Model{ID: "123"}},
+	}, dispatcher.commands)
+
+	v, err = repo.Load(ctx, "fulfillment-123")
+	assert.Nil(t, err)
+	assert.True(t, v.(*Fulfillment).Paid)
+
+	// Test - Handler failures save nothing
+
+	pm = command.NewProcessManager(repo, dispatcher, command.CorrelatorFunc(func(event eventsource.Event) (string, bool) {
+		return "fulfillment-" + event.AggregateID(), true
+	}))
+	err = pm.Handle(ctx, &UserCreated{Model: eventsource.Model{ID: "456", Version: 1}}, 0)
+	assert.True(t, errors.Is(err, command.ErrHandler))
+
+	// Test - The aggregate of the Repository must implement Saga
+
+	users := eventsource.New(&User{})
+	users.Bind(UserCreated{}, UserEmailChanged{})
+
+	pm = command.NewProcessManager(users, dispatcher, correlateFulfillment)
+	err = pm.Handle(ctx, &OrderPlaced{ModelWithMetadata: eventsource.ModelWithMetadata{Model: eventsource.Model{ID: "123", Version: 1}}}, 0)
+	assert.True(t, errors.Is(err, command.ErrAggregateNotSaga))
+}
+
+func TestProcessManager_Projection(t *testing.T) {
+	ctx := context.Background()
+
+	// Test - Events saved by other aggregates are fed to the sagas by a projection.Runner
+
+	store := eventsource.NewMemoryStore()
+	serializer := eventsource.JSONSerializer()
+	serializer.Bind(OrderPlaced{}, PaymentReceived{})
+
+	for _, event := range []eventsource.Event{
+		OrderPlaced{ModelWithMetadata: eventsource.ModelWithMetadata{Model: eventsource.Model{ID: "123", Version: 1}}, Amount: 100},
+		PaymentReceived{Model: eventsource.Model{ID: "payment-123", Version: 1}, OrderID: "123"},
+	} {
+		record, err := serializer.Serialize(event)
+		assert.Nil(t, err)
+		assert.Nil(t, store.Save(ctx, event.AggregateID(), record))
+	}
+
+	repo := eventsource.New(&Fulfillment{})
+	repo.Bind(FulfillmentStarted{}, FulfillmentPaid{})
+
+	dispatcher := &recorder{}
+	runner := projection.New("fulfillment", store, serializer, command.NewProcessManager(repo, dispatcher, correlateFulfillment))
+
+	position, err := runner.CatchUp(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), position)
+	assert.Len(t, dispatcher.commands, 2)
+
+	v, err := repo.Load(ctx, "fulfillment-123")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), v.(*Fulfillment).Position)
+
+	// Test - Events delivered again are recognized by their position, so no commands are dispatched again
+
+	position, err = runner.Rebuild(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), position)
+	assert.Len(t, dispatcher.commands, 2)
+}
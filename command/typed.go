@@ -0,0 +1,15 @@
+package command
+
+import "github.com/savaki/eventsource"
+
+// AggregateHandler is an Aggregate that handles commands applied to it
+type AggregateHandler interface {
+	eventsource.Aggregate
+	Handler
+}
+
+// NewTyped instantiates a new Dispatcher for aggregates of type T using the Options provided; see NewDispatcher.
+// Unlike NewDispatcher, the requirement that the aggregate implement Handler is checked at compile time.
+func NewTyped[T AggregateHandler](repo *eventsource.TypedRepository[T], opts ...Option) Dispatcher {
+	return NewDispatcher(repo.Repository(), opts...)
+}
@@ -0,0 +1,46 @@
+package command_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/savaki/eventsource"
+	"github.com/savaki/eventsource/command"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTyped(t *testing.T) {
+	repo := eventsource.NewTyped[*User]()
+	repo.Bind(UserCreated{}, UserEmailChanged{})
+
+	ctx := context.Background()
+	id := "123"
+
+	var dispatched []command.Interface
+	dispatcher := command.NewTyped(repo,
+		command.WithRetry(1),
+		command.WithMiddleware(command.After(func(ctx context.Context, cmd command.Interface, events []eventsource.Event, err error) {
+			dispatched = append(dispatched, cmd)
+		})),
+	)
+	err := dispatcher.Dispatch(ctx, CreateCommand{
+		Model: command.Model{ID: id},
+		Name:  "John Doe",
+	})
+	assert.Nil(t, err)
+
+	err = dispatcher.Dispatch(ctx, ChangeEmailCommand{
+		Model: command.Model{ID: id},
+		Email: "jane.doe@example.com",
+	})
+	assert.Nil(t, err)
+
+	user, err := repo.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, "John Doe", user.Name)
+	assert.Equal(t, "jane.doe@example.com", user.Email)
+
+	// Test - Options such as middleware apply to typed dispatchers
+
+	assert.Len(t, dispatched, 2)
+}
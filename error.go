@@ -15,6 +15,28 @@ const (
 	UnboundEventType  = "UnboundEventType"
 	AggregateNotFound = "AggregateNotFound"
 	UnhandledEvent    = "UnhandledEvent"
+	SnapshotNotFound  = "SnapshotNotFound"
+)
+
+// Sentinel errors, one for each code.  Any Error matches the sentinel of its code when using errors.Is e.g.
+//
+//	if errors.Is(err, eventsource.ErrDuplicateVersion) {
+//		// reload the aggregate and retry
+//	}
+var (
+	ErrAggregateNil      = NewError(nil, AggregateNil, "aggregate nil")
+	ErrDuplicateID       = NewError(nil, DuplicateID, "duplicate id")
+	ErrDuplicateVersion  = NewError(nil, DuplicateVersion, "duplicate version")
+	ErrDuplicateAt       = NewError(nil, DuplicateAt, "duplicate at")
+	ErrDuplicateType     = NewError(nil, DuplicateType, "duplicate type")
+	ErrInvalidID         = NewError(nil, InvalidID, "invalid id")
+	ErrInvalidAt         = NewError(nil, InvalidAt, "invalid at")
+	ErrInvalidVersion    = NewError(nil, InvalidVersion, "invalid version")
+	ErrInvalidEncoding   = NewError(nil, InvalidEncoding, "invalid encoding")
+	ErrUnboundEventType  = NewError(nil, UnboundEventType, "unbound event type")
+	ErrAggregateNotFound = NewError(nil, AggregateNotFound, "aggregate not found")
+	ErrUnhandledEvent    = NewError(nil, UnhandledEvent, "unhandled event")
+	ErrSnapshotNotFound  = NewError(nil, SnapshotNotFound, "snapshot not found")
 )
 
 // Error provides a standardized error interface for eventsource
@@ -43,6 +65,15 @@ func (b *baseErr) Message() string { return b.message }
 func (b *baseErr) Error() string   { return fmt.Sprintf("[%v] %v - %v", b.code, b.message, b.cause) }
 func (b *baseErr) String() string  { return b.Error() }
 
+// Unwrap returns the cause, allowing errors.Is and errors.As to examine it
+func (b *baseErr) Unwrap() error { return b.cause }
+
+// Is reports whether target is an Error with the same code
+func (b *baseErr) Is(target error) bool {
+	v, ok := target.(Error)
+	return ok && v.Code() == b.code
+}
+
 func NewError(err error, code, format string, args ...interface{}) error {
 	return &baseErr{
 		code:    code,
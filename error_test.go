@@ -0,0 +1,42 @@
+package eventsource_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/savaki/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorIs(t *testing.T) {
+	err := eventsource.NewError(io.EOF, eventsource.DuplicateVersion, "aggregate, %v, already contains version %v", "abc", 1)
+
+	assert.True(t, errors.Is(err, eventsource.ErrDuplicateVersion))
+	assert.False(t, errors.Is(err, eventsource.ErrAggregateNotFound))
+	assert.True(t, errors.Is(err, io.EOF), "expected cause to be unwrapped")
+
+	var v eventsource.Error
+	assert.True(t, errors.As(err, &v))
+	assert.Equal(t, eventsource.DuplicateVersion, v.Code())
+}
+
+func TestRepositoryErrors(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	registry := eventsource.New(&Entity{})
+	registry.Bind(EntityCreated{}, EntityNameSet{}, EntityRenamed{})
+
+	_, err := registry.Load(ctx, id)
+	assert.True(t, errors.Is(err, eventsource.ErrAggregateNotFound))
+
+	err = registry.Save(ctx, &EntityRenamed{Model: eventsource.Model{ID: id, Version: 1}})
+	assert.Nil(t, err)
+
+	// Test - Entity does not handle EntityRenamed
+
+	_, err = registry.Load(ctx, id)
+	assert.True(t, errors.Is(err, eventsource.ErrUnhandledEvent))
+}
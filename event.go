@@ -24,6 +24,18 @@ type EventTyper interface {
 	EventType() string
 }
 
+// MetadataProvider is an optional interface that can be applied to an Event to provide metadata to be saved with it
+type MetadataProvider interface {
+	// EventMetadata returns the metadata to be saved with the event
+	EventMetadata() map[string]string
+}
+
+// MetadataReceiver is an optional interface that can be applied to an Event to receive the metadata saved with it
+type MetadataReceiver interface {
+	// SetEventMetadata is called with the saved metadata after the event is deserialized
+	SetEventMetadata(metadata map[string]string)
+}
+
 // Model provides a default implementation of an Event that is suitable for being embedded
 type Model struct {
 	// ID contains the AggregateID
@@ -50,3 +62,22 @@ func (m Model) EventVersion() int {
 func (m Model) EventAt() time.Time {
 	return m.At
 }
+
+// ModelWithMetadata extends Model with the metadata saved alongside the event.  Embed ModelWithMetadata, rather than
+// Model, to opt in to receiving metadata; unlike Model, events embedding ModelWithMetadata are not comparable.
+type ModelWithMetadata struct {
+	Model
+
+	// Metadata contains optional information about the event; saved alongside, rather than within, the event
+	Metadata map[string]string `json:"-"`
+}
+
+// EventMetadata implements the MetadataProvider interface
+func (m ModelWithMetadata) EventMetadata() map[string]string {
+	return m.Metadata
+}
+
+// SetEventMetadata implements the MetadataReceiver interface
+func (m *ModelWithMetadata) SetEventMetadata(metadata map[string]string) {
+	m.Metadata = metadata
+}
@@ -1,6 +1,7 @@
 package eventsource_test
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -38,6 +39,17 @@ var (
 	event eventsource.Event
 )
 
+func TestModelComparable(t *testing.T) {
+	assert.True(t, reflect.TypeOf(Embedded{}).Comparable())
+	assert.False(t, reflect.TypeOf(eventsource.ModelWithMetadata{}).Comparable())
+
+	var v interface{} = &eventsource.ModelWithMetadata{}
+	_, ok := v.(eventsource.MetadataProvider)
+	assert.True(t, ok)
+	_, ok = v.(eventsource.MetadataReceiver)
+	assert.True(t, ok)
+}
+
 func BenchmarkInspectEmbedded(b *testing.B) {
 	id := "123"
 	var item interface{} = Embedded{
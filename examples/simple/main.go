@@ -69,7 +69,7 @@ func (item *User) On(event eventsource.Event) bool {
 }
 
 func main() {
-	repo := eventsource.New(&User{})
+	repo := eventsource.NewTyped[*User]()
 	err := repo.Bind(
 		UserCreated{},
 		UserNameSet{},
@@ -96,11 +96,10 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	v, err := repo.Load(ctx, id)
+	user, err := repo.Load(ctx, id)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	user := v.(*User)
 	fmt.Printf("Hello %v %v\n", user.Name, user.Email) // prints "Hello Joe Public joe.public@example.com"
 }
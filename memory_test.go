@@ -0,0 +1,107 @@
+package eventsource_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/savaki/eventsource"
+	"github.com/savaki/eventsource/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.TestStore(t, func(t *testing.T) eventsource.Store {
+		return eventsource.NewMemoryStore()
+	})
+}
+
+func TestMemoryStore_Subscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := eventsource.NewMemoryStore()
+
+	r1 := eventsource.Record{Version: 1, Data: []byte("a")}
+	r2 := eventsource.Record{Version: 2, Data: []byte("b")}
+	r3 := eventsource.Record{Version: 1, Data: []byte("c")}
+
+	assert.Nil(t, store.Save(ctx, "abc", r1))
+
+	// Test - Only records saved after subscribing are received, in position order
+
+	ch := store.Subscribe(ctx)
+	assert.Nil(t, store.Save(ctx, "abc", r2))
+	assert.Nil(t, store.Save(ctx, "def", r3))
+
+	expected := []eventsource.StreamRecord{
+		{Record: r2, AggregateID: "abc", Position: 2},
+		{Record: r3, AggregateID: "def", Position: 3},
+	}
+	for _, want := range expected {
+		select {
+		case got := <-ch:
+			assert.Equal(t, want, got)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for record at position %v", want.Position)
+		}
+	}
+
+	// Test - The channel is closed once the context is done
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for subscription to close")
+	}
+}
+
+func TestMemoryStore_SubscribeFilter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := eventsource.NewMemoryStore()
+
+	r1 := eventsource.Record{Version: 1, Type: "Created", Data: []byte("a")}
+	r2 := eventsource.Record{Version: 2, Type: "Renamed", Data: []byte("b")}
+	r3 := eventsource.Record{Version: 1, Type: "Renamed", Data: []byte("c")}
+	r4 := eventsource.Record{Version: 3, Type: "Renamed", Data: []byte("d")}
+
+	// Test - Only records accepted by every filter are received; skipped records still advance the position
+
+	ch := store.Subscribe(ctx,
+		eventsource.FilterAggregateID("abc"),
+		eventsource.FilterEventType("Renamed"),
+	)
+	assert.Nil(t, store.Save(ctx, "abc", r1, r2))
+	assert.Nil(t, store.Save(ctx, "def", r3))
+	assert.Nil(t, store.Save(ctx, "abc", r4))
+
+	expected := []eventsource.StreamRecord{
+		{Record: r2, AggregateID: "abc", Position: 2},
+		{Record: r4, AggregateID: "abc", Position: 4},
+	}
+	for _, want := range expected {
+		select {
+		case got := <-ch:
+			assert.Equal(t, want, got)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for record at position %v", want.Position)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	record := eventsource.StreamRecord{
+		Record:      eventsource.Record{Type: "Created"},
+		AggregateID: "abc",
+	}
+
+	assert.True(t, eventsource.Match(record))
+	assert.True(t, eventsource.Match(record, eventsource.FilterAggregateID("def", "abc")))
+	assert.True(t, eventsource.Match(record, eventsource.FilterEventType("Created")))
+	assert.False(t, eventsource.Match(record, eventsource.FilterAggregateID("def")))
+	assert.False(t, eventsource.Match(record, eventsource.FilterAggregateID("abc"), eventsource.FilterEventType("Renamed")))
+}
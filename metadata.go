@@ -0,0 +1,44 @@
+package eventsource
+
+import "context"
+
+// Well known metadata keys
+const (
+	// MetadataCorrelationID identifies the request or workflow the event belongs to
+	MetadataCorrelationID = "correlation_id"
+
+	// MetadataCausationID identifies the command or event that caused the event
+	MetadataCausationID = "causation_id"
+
+	// MetadataActor identifies who, or what, caused the event
+	MetadataActor = "actor"
+)
+
+type metadataKey struct{}
+
+// ContextWithMetadata returns a copy of ctx whose metadata is the existing metadata merged with the metadata
+// provided; Repository.Save attaches the context metadata to each of the events saved
+func ContextWithMetadata(ctx context.Context, metadata map[string]string) context.Context {
+	return context.WithValue(ctx, metadataKey{}, mergeMetadata(MetadataFromContext(ctx), metadata))
+}
+
+// MetadataFromContext returns a copy of the metadata attached to the context or nil if none has been attached
+func MetadataFromContext(ctx context.Context) map[string]string {
+	metadata, _ := ctx.Value(metadataKey{}).(map[string]string)
+	return mergeMetadata(nil, metadata)
+}
+
+// mergeMetadata returns a new map containing the contents of each of the maps provided with latter maps taking
+// precedence; nil is returned if the result would be empty
+func mergeMetadata(maps ...map[string]string) map[string]string {
+	var merged map[string]string
+	for _, m := range maps {
+		for k, v := range m {
+			if merged == nil {
+				merged = map[string]string{}
+			}
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,84 @@
+package eventsource_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/savaki/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+type AuditCreated struct {
+	eventsource.ModelWithMetadata
+}
+
+type AuditRenamed struct {
+	eventsource.ModelWithMetadata
+	Name string
+}
+
+type Audited struct {
+	Actors []string
+}
+
+func (a *Audited) On(event eventsource.Event) bool {
+	switch v := event.(type) {
+	case *AuditCreated:
+		a.Actors = append(a.Actors, v.Metadata[eventsource.MetadataActor])
+
+	case *AuditRenamed:
+		a.Actors = append(a.Actors, v.Metadata[eventsource.MetadataActor])
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+func TestContextWithMetadata(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, eventsource.MetadataFromContext(ctx))
+
+	ctx = eventsource.ContextWithMetadata(ctx, map[string]string{
+		eventsource.MetadataActor:         "joe",
+		eventsource.MetadataCorrelationID: "abc",
+	})
+	ctx = eventsource.ContextWithMetadata(ctx, map[string]string{
+		eventsource.MetadataActor: "jane",
+	})
+
+	assert.Equal(t, map[string]string{
+		eventsource.MetadataActor:         "jane",
+		eventsource.MetadataCorrelationID: "abc",
+	}, eventsource.MetadataFromContext(ctx))
+}
+
+func TestSaveMetadata(t *testing.T) {
+	id := "123"
+	ctx := eventsource.ContextWithMetadata(context.Background(), map[string]string{
+		eventsource.MetadataActor: "joe",
+	})
+
+	registry := eventsource.New(&Audited{})
+	registry.Bind(AuditCreated{}, AuditRenamed{})
+
+	err := registry.Save(ctx,
+		&AuditCreated{
+			ModelWithMetadata: eventsource.ModelWithMetadata{
+				Model: eventsource.Model{ID: id, Version: 1},
+			},
+		},
+		&AuditRenamed{
+			ModelWithMetadata: eventsource.ModelWithMetadata{
+				Model:    eventsource.Model{ID: id, Version: 2},
+				Metadata: map[string]string{eventsource.MetadataActor: "jane"},
+			},
+		},
+	)
+	assert.Nil(t, err)
+
+	v, err := registry.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"joe", "jane"}, v.(*Audited).Actors)
+}
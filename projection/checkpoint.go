@@ -0,0 +1,46 @@
+package projection
+
+import (
+	"context"
+	"sync"
+)
+
+// CheckpointStore records the position in the log each projection has processed up to
+type CheckpointStore interface {
+	// Load returns the position of the last record processed by the named projection; 0 if no checkpoint exists
+	Load(ctx context.Context, name string) (int64, error)
+
+	// Save records position as the last record processed by the named projection
+	Save(ctx context.Context, name string, position int64) error
+}
+
+// MemoryCheckpointStore provides an in-memory implementation of CheckpointStore
+type MemoryCheckpointStore struct {
+	mux       *sync.Mutex
+	positions map[string]int64
+}
+
+// NewMemoryCheckpointStore returns a new, empty MemoryCheckpointStore
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{
+		mux:       &sync.Mutex{},
+		positions: map[string]int64{},
+	}
+}
+
+// Load implements the CheckpointStore interface
+func (m *MemoryCheckpointStore) Load(ctx context.Context, name string) (int64, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.positions[name], nil
+}
+
+// Save implements the CheckpointStore interface
+func (m *MemoryCheckpointStore) Save(ctx context.Context, name string, position int64) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.positions[name] = position
+	return nil
+}
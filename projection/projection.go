@@ -0,0 +1,284 @@
+// Package projection builds read models from the log of events held by an eventsource.Store.
+//
+// A Runner reads the log from the last checkpoint of its Projector, deserializes each record, and passes the event to
+// the Projector along with its position.  Checkpoints are saved after each batch of records, so a Projector may see
+// an event again after a restart and should apply events idempotently.
+//
+// Positions need not be contiguous, and records need not become visible in position order; e.g. the offsets of the
+// sqlstore are assigned on insert, but become visible when the transaction commits, and are never reused once a
+// transaction rolls back.  When a Runner finds a gap between its checkpoint and the next record, it waits at the gap
+// until either the missing records appear or the gap timeout passes; see WithGapTimeout.  Events are therefore
+// projected in position order, and none are skipped, provided each record becomes visible within the gap timeout of
+// the records following it.  Records that become visible later than that are skipped.  Runners restricted to
+// WithEventTypes can not distinguish gaps from records of other types, so offer no such guarantee.
+//
+// A Runner can not tell a pending record from a permanent hole, so every hole costs each Runner the full gap timeout
+// when first reached.  Holes are left by any save that is rolled back once its offsets are assigned, e.g. saves
+// rejected with eventsource.DuplicateVersion, including those retried by the command dispatcher.  The log is assumed
+// to begin at its first record, wherever that is; e.g. sqlstore offsets begin at 10000.
+package projection
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/savaki/eventsource"
+)
+
+const (
+	// DefaultBatchSize is the default number of records read from the log at a time
+	DefaultBatchSize = 100
+
+	// DefaultPollInterval is the default time between reads of the log once the Runner has caught up
+	DefaultPollInterval = time.Second
+
+	// DefaultGapTimeout is the default time the Runner waits for the records missing from a gap in the log
+	DefaultGapTimeout = 10 * time.Second
+)
+
+var (
+	errTypeReaderRequired = errors.New("store does not implement eventsource.TypeReader; required by WithEventTypes")
+)
+
+// Projector builds a read model from events
+type Projector interface {
+	// Project applies the event, found at position within the log, to the read model
+	Project(ctx context.Context, event eventsource.Event, position int64) error
+}
+
+// ProjectorFunc provides a func implementation of Projector
+type ProjectorFunc func(ctx context.Context, event eventsource.Event, position int64) error
+
+// Project implements the Projector interface
+func (fn ProjectorFunc) Project(ctx context.Context, event eventsource.Event, position int64) error {
+	return fn(ctx, event, position)
+}
+
+// Resetter is an optional interface a Projector may implement to discard its read model prior to a Rebuild
+type Resetter interface {
+	// Reset discards the read model
+	Reset(ctx context.Context) error
+}
+
+// Runner feeds the events from the log of a Store to a Projector.  Calls to Run, CatchUp, and Rebuild are serialized,
+// so a Runner may be shared between goroutines, but only one of them makes progress at a time.
+type Runner struct {
+	name         string
+	reader       eventsource.Reader
+	serializer   eventsource.Serializer
+	projector    Projector
+	checkpoints  CheckpointStore
+	eventTypes   []string
+	batchSize    int
+	pollInterval time.Duration
+	gapTimeout   time.Duration
+
+	// mu serializes catch ups, and guards gapAfter and gapSince, which record the position preceding the gap the
+	// Runner is waiting on and when it was found
+	mu       sync.Mutex
+	gapAfter int64
+	gapSince time.Time
+}
+
+// Option provides optional configuration to the Runner
+type Option func(*Runner)
+
+// WithCheckpointStore specifies where checkpoints are saved; defaults to a MemoryCheckpointStore
+func WithCheckpointStore(checkpoints CheckpointStore) Option {
+	return func(r *Runner) {
+		r.checkpoints = checkpoints
+	}
+}
+
+// WithEventTypes restricts the records read to those of the event types provided; requires the Store to implement
+// eventsource.TypeReader
+func WithEventTypes(eventTypes ...string) Option {
+	return func(r *Runner) {
+		r.eventTypes = eventTypes
+	}
+}
+
+// WithBatchSize specifies the number of records read from the log at a time
+func WithBatchSize(batchSize int) Option {
+	return func(r *Runner) {
+		if batchSize > 0 {
+			r.batchSize = batchSize
+		}
+	}
+}
+
+// WithPollInterval specifies the time between reads of the log once the Runner has caught up
+func WithPollInterval(pollInterval time.Duration) Option {
+	return func(r *Runner) {
+		r.pollInterval = pollInterval
+	}
+}
+
+// WithGapTimeout specifies how long the Runner waits for the records missing from a gap in the log before skipping
+// them; 0 skips gaps immediately.  Defaults to DefaultGapTimeout.  While waiting, the log is read again each poll
+// interval.
+func WithGapTimeout(gapTimeout time.Duration) Option {
+	return func(r *Runner) {
+		r.gapTimeout = gapTimeout
+	}
+}
+
+// New returns a Runner that projects the events of reader, typically an eventsource.Store implementing
+// eventsource.Reader, onto projector.  name identifies the checkpoint of the projection.  Records whose event types
+// are not bound to serializer are skipped.
+func New(name string, reader eventsource.Reader, serializer eventsource.Serializer, projector Projector, opts ...Option) *Runner {
+	r := &Runner{
+		name:         name,
+		reader:       reader,
+		serializer:   serializer,
+		projector:    projector,
+		checkpoints:  NewMemoryCheckpointStore(),
+		batchSize:    DefaultBatchSize,
+		pollInterval: DefaultPollInterval,
+		gapTimeout:   DefaultGapTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Run catches up from the last checkpoint and then follows new records until ctx is done, returning the error of ctx
+func (r *Runner) Run(ctx context.Context) error {
+	for {
+		if _, err := r.CatchUp(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// CatchUp projects the records following the last checkpoint until no more remain, returning the new checkpoint.  On
+// reaching a gap, CatchUp waits until either the missing records appear or the gap timeout passes, so a permanent
+// hole delays it by the gap timeout.  If ctx is done while waiting, the error of ctx is returned.
+func (r *Runner) CatchUp(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.catchUp(ctx)
+}
+
+func (r *Runner) catchUp(ctx context.Context) (int64, error) {
+	position, err := r.checkpoints.Load(ctx, r.name)
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		records, err := r.read(ctx, position)
+		if err != nil {
+			return position, err
+		}
+		if len(records) == 0 {
+			return position, nil
+		}
+
+		start, wait := position, time.Duration(0)
+		for _, record := range records {
+			if wait = r.waitForGap(position, record.Position); wait > 0 {
+				break
+			}
+			if err := r.project(ctx, record); err != nil {
+				return position, err
+			}
+			position = record.Position
+		}
+
+		if position > start {
+			if err := r.checkpoints.Save(ctx, r.name, position); err != nil {
+				return position, err
+			}
+		}
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return position, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+	}
+}
+
+// Rebuild discards the read model, when the Projector implements Resetter, and projects the log from the beginning
+// as CatchUp does
+func (r *Runner) Rebuild(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if v, ok := r.projector.(Resetter); ok {
+		if err := v.Reset(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := r.checkpoints.Save(ctx, r.name, 0); err != nil {
+		return 0, err
+	}
+
+	r.gapSince = time.Time{}
+	return r.catchUp(ctx)
+}
+
+// waitForGap returns how long to wait before reading the log again if records are missing between position and next,
+// and the gap was found less than the gap timeout ago; otherwise 0.  Position 0 is the start of the log, so never
+// precedes a gap.
+func (r *Runner) waitForGap(position, next int64) time.Duration {
+	if position == 0 || next <= position+1 || len(r.eventTypes) > 0 || r.gapTimeout <= 0 {
+		r.gapSince = time.Time{}
+		return 0
+	}
+
+	now := time.Now()
+	if r.gapSince.IsZero() || r.gapAfter != position {
+		r.gapAfter, r.gapSince = position, now
+	}
+
+	remaining := r.gapTimeout - now.Sub(r.gapSince)
+	if remaining <= 0 {
+		r.gapSince = time.Time{}
+		return 0
+	}
+	if r.pollInterval > 0 && r.pollInterval < remaining {
+		return r.pollInterval
+	}
+	return remaining
+}
+
+func (r *Runner) read(ctx context.Context, position int64) ([]eventsource.StreamRecord, error) {
+	if len(r.eventTypes) == 0 {
+		return r.reader.Read(ctx, position, r.batchSize)
+	}
+
+	v, ok := r.reader.(eventsource.TypeReader)
+	if !ok {
+		return nil, errTypeReaderRequired
+	}
+
+	return v.ReadTypes(ctx, position, r.batchSize, r.eventTypes...)
+}
+
+func (r *Runner) project(ctx context.Context, record eventsource.StreamRecord) error {
+	event, err := r.serializer.Deserialize(record.Record)
+	if errors.Is(err, eventsource.ErrUnboundEventType) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return r.projector.Project(ctx, event, record.Position)
+}
@@ -0,0 +1,295 @@
+package projection_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/savaki/eventsource"
+	"github.com/savaki/eventsource/projection"
+	"github.com/stretchr/testify/assert"
+)
+
+type UserCreated struct {
+	eventsource.Model
+	Name string
+}
+
+type UserNameSet struct {
+	eventsource.Model
+	Name string
+}
+
+type UserDeleted struct {
+	eventsource.Model
+}
+
+// Names projects the current name of each user
+type Names struct {
+	mux       sync.Mutex
+	names     map[string]string
+	positions []int64
+	resets    int
+}
+
+func (n *Names) Project(ctx context.Context, event eventsource.Event, position int64) error {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	if n.names == nil {
+		n.names = map[string]string{}
+	}
+
+	switch v := event.(type) {
+	case *UserCreated:
+		n.names[v.ID] = v.Name
+	case *UserNameSet:
+		n.names[v.ID] = v.Name
+	}
+	n.positions = append(n.positions, position)
+
+	return nil
+}
+
+func (n *Names) Reset(ctx context.Context) error {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	n.names = nil
+	n.positions = nil
+	n.resets++
+	return nil
+}
+
+func (n *Names) Name(id string) string {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	return n.names[id]
+}
+
+func setup() (eventsource.Repository, *eventsource.MemoryStore, eventsource.Serializer) {
+	store := eventsource.NewMemoryStore()
+	serializer := eventsource.JSONSerializer()
+	serializer.Bind(UserCreated{}, UserNameSet{})
+
+	repo := eventsource.New(&User{}, eventsource.WithStore(store))
+	repo.Bind(UserCreated{}, UserNameSet{}, UserDeleted{})
+
+	return repo, store, serializer
+}
+
+type User struct{}
+
+func (a *User) On(event eventsource.Event) bool { return true }
+
+func TestRunner(t *testing.T) {
+	ctx := context.Background()
+	repo, store, serializer := setup()
+
+	err := repo.Save(ctx,
+		&UserCreated{Model: eventsource.Model{ID: "a", Version: 1}, Name: "Jones"},
+		&UserNameSet{Model: eventsource.Model{ID: "a", Version: 2}, Name: "Sarah"},
+		&UserDeleted{Model: eventsource.Model{ID: "a", Version: 3}},
+	)
+	assert.Nil(t, err)
+
+	names := &Names{}
+	checkpoints := projection.NewMemoryCheckpointStore()
+	runner := projection.New("names", store, serializer, names,
+		projection.WithCheckpointStore(checkpoints),
+		projection.WithBatchSize(2),
+	)
+
+	position, err := runner.CatchUp(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), position)
+	assert.Equal(t, "Sarah", names.Name("a"))
+	assert.Equal(t, []int64{1, 2}, names.positions, "expected unbound UserDeleted to be skipped")
+
+	saved, err := checkpoints.Load(ctx, "names")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), saved)
+
+	// Test - A second runner resumes from the checkpoint
+
+	err = repo.Save(ctx, &UserCreated{Model: eventsource.Model{ID: "b", Version: 1}, Name: "Joe"})
+	assert.Nil(t, err)
+
+	resumed := &Names{}
+	runner = projection.New("names", store, serializer, resumed, projection.WithCheckpointStore(checkpoints))
+	position, err = runner.CatchUp(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), position)
+	assert.Equal(t, []int64{4}, resumed.positions)
+
+	// Test - Rebuild projects the log from the beginning
+
+	position, err = runner.Rebuild(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), position)
+	assert.Equal(t, 1, resumed.resets)
+	assert.Equal(t, []int64{1, 2, 4}, resumed.positions)
+	assert.Equal(t, "Sarah", resumed.Name("a"))
+}
+
+func TestRunnerEventTypes(t *testing.T) {
+	ctx := context.Background()
+	repo, store, serializer := setup()
+
+	err := repo.Save(ctx,
+		&UserCreated{Model: eventsource.Model{ID: "a", Version: 1}, Name: "Jones"},
+		&UserNameSet{Model: eventsource.Model{ID: "a", Version: 2}, Name: "Sarah"},
+	)
+	assert.Nil(t, err)
+
+	names := &Names{}
+	runner := projection.New("created", store, serializer, names, projection.WithEventTypes("UserCreated"))
+	_, err = runner.CatchUp(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "Jones", names.Name("a"))
+	assert.Equal(t, []int64{1}, names.positions)
+}
+
+func TestRunnerRun(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	repo, store, serializer := setup()
+
+	names := &Names{}
+	runner := projection.New("names", store, serializer, names, projection.WithPollInterval(10*time.Millisecond))
+
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+
+	err := repo.Save(ctx, &UserCreated{Model: eventsource.Model{ID: "a", Version: 1}, Name: "Jones"})
+	assert.Nil(t, err)
+
+	for names.Name("a") == "" && ctx.Err() == nil {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, "Jones", names.Name("a"))
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
+
+// hidingReader hides the records at the positions held for the number of reads held, or for good when negative,
+// simulating records yet to be committed or rolled back
+type hidingReader struct {
+	*eventsource.MemoryStore
+	mux    sync.Mutex
+	hidden map[int64]int
+}
+
+func (h *hidingReader) Read(ctx context.Context, startingPosition int64, recordCount int) ([]eventsource.StreamRecord, error) {
+	records, err := h.MemoryStore.Read(ctx, startingPosition, recordCount)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	visible := make([]eventsource.StreamRecord, 0, len(records))
+	for _, record := range records {
+		if reads, ok := h.hidden[record.Position]; !ok || reads == 0 {
+			visible = append(visible, record)
+		}
+	}
+	for position, reads := range h.hidden {
+		if reads > 0 {
+			h.hidden[position] = reads - 1
+		}
+	}
+	return visible, nil
+}
+
+func TestRunnerGap(t *testing.T) {
+	ctx := context.Background()
+	repo, store, serializer := setup()
+
+	for version := 1; version <= 4; version++ {
+		err := repo.Save(ctx, &UserNameSet{Model: eventsource.Model{ID: "a", Version: version}, Name: "Jones"})
+		assert.Nil(t, err)
+	}
+
+	// Test - The Runner waits at a gap until the records filling it are visible, then projects them in position order
+
+	reader := &hidingReader{MemoryStore: store, hidden: map[int64]int{2: 2}}
+	names := &Names{}
+	runner := projection.New("names", reader, serializer, names,
+		projection.WithGapTimeout(time.Minute),
+		projection.WithPollInterval(time.Millisecond),
+	)
+
+	position, err := runner.CatchUp(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), position)
+	assert.Equal(t, []int64{1, 2, 3, 4}, names.positions)
+
+	// Test - Permanent holes are skipped once the gap timeout has passed
+
+	reader = &hidingReader{MemoryStore: store, hidden: map[int64]int{2: -1}}
+	names = &Names{}
+	runner = projection.New("names", reader, serializer, names,
+		projection.WithGapTimeout(50*time.Millisecond),
+		projection.WithPollInterval(5*time.Millisecond),
+	)
+
+	begin := time.Now()
+	position, err = runner.CatchUp(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), position)
+	assert.Equal(t, []int64{1, 3, 4}, names.positions)
+	assert.True(t, time.Since(begin) >= 50*time.Millisecond)
+
+	// Test - CatchUp reports ctx being done while waiting on a gap rather than returning success
+
+	reader = &hidingReader{MemoryStore: store, hidden: map[int64]int{3: -1}}
+	names = &Names{}
+	runner = projection.New("names", reader, serializer, names,
+		projection.WithGapTimeout(time.Minute),
+		projection.WithPollInterval(time.Millisecond),
+	)
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	position, err = runner.CatchUp(timeout)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, int64(2), position)
+	assert.Equal(t, []int64{1, 2}, names.positions)
+}
+
+func TestRunnerLogOffset(t *testing.T) {
+	ctx := context.Background()
+	repo, store, serializer := setup()
+
+	for version := 1; version <= 4; version++ {
+		err := repo.Save(ctx, &UserNameSet{Model: eventsource.Model{ID: "a", Version: version}, Name: "Jones"})
+		assert.Nil(t, err)
+	}
+
+	// the log begins at position 3, as the sqlstore log begins at its initial offset
+	reader := &hidingReader{MemoryStore: store, hidden: map[int64]int{1: -1, 2: -1}}
+	names := &Names{}
+	runner := projection.New("names", reader, serializer, names, projection.WithGapTimeout(time.Minute))
+
+	// Test - The start of the log is not treated as a gap
+
+	position, err := runner.CatchUp(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), position)
+	assert.Equal(t, []int64{3, 4}, names.positions)
+
+	// Test - Rebuild projects the log from its start
+
+	position, err = runner.Rebuild(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), position)
+	assert.Equal(t, []int64{3, 4}, names.positions)
+	assert.Equal(t, 1, names.resets)
+}
@@ -0,0 +1,74 @@
+package dynamodbstore
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/savaki/eventsource"
+)
+
+const (
+	// DefaultCheckpointHashKey is the hash key (partition key) of the checkpoint table
+	DefaultCheckpointHashKey = "name"
+)
+
+const (
+	checkpointPosition = "position"
+	checkpointAt       = "at"
+)
+
+// CheckpointStore represents a dynamodb backed projection.CheckpointStore
+type CheckpointStore struct {
+	tableName string
+	api       *dynamodb.DynamoDB
+}
+
+// Load implements the projection.CheckpointStore interface
+func (s *CheckpointStore) Load(ctx context.Context, name string) (int64, error) {
+	out, err := s.api.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			DefaultCheckpointHashKey: {S: aws.String(name)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	av, ok := out.Item[checkpointPosition]
+	if !ok {
+		return 0, nil
+	}
+
+	return strconv.ParseInt(aws.StringValue(av.N), 10, 64)
+}
+
+// Save implements the projection.CheckpointStore interface
+func (s *CheckpointStore) Save(ctx context.Context, name string, position int64) error {
+	_, err := s.api.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			DefaultCheckpointHashKey: {S: aws.String(name)},
+			checkpointPosition:       {N: aws.String(strconv.FormatInt(position, 10))},
+			checkpointAt:             {N: aws.String(eventsource.Now().String())},
+		},
+	})
+	return err
+}
+
+// NewCheckpointStore returns a CheckpointStore backed by the table provided; see MakeCreateCheckpointTableInput.
+// Accepts the same options as New, although only WithRegion and WithDynamoDB apply.
+func NewCheckpointStore(tableName string, opts ...Option) (*CheckpointStore, error) {
+	store, err := New(tableName, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CheckpointStore{
+		tableName: tableName,
+		api:       store.api,
+	}, nil
+}
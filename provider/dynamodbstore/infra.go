@@ -55,3 +55,97 @@ func MakeCreateTableInput(tableName string, readCapacity, writeCapacity int64, o
 
 	return input
 }
+
+// MakeCreateLogTableInput is a utility tool to write the table definition for the log table specified by WithLogTable
+func MakeCreateLogTableInput(tableName string, readCapacity, writeCapacity int64) *dynamodb.CreateTableInput {
+	return &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{
+				AttributeName: aws.String(logBucket),
+				AttributeType: aws.String("N"),
+			},
+			{
+				AttributeName: aws.String(logPosition),
+				AttributeType: aws.String("N"),
+			},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{
+				AttributeName: aws.String(logBucket),
+				KeyType:       aws.String("HASH"),
+			},
+			{
+				AttributeName: aws.String(logPosition),
+				KeyType:       aws.String("RANGE"),
+			},
+		},
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(readCapacity),
+			WriteCapacityUnits: aws.Int64(writeCapacity),
+		},
+	}
+}
+
+// MakeCreateSnapshotTableInput is a utility tool to write the table definition for the table used by SnapshotStore
+func MakeCreateSnapshotTableInput(tableName string, readCapacity, writeCapacity int64, opts ...Option) *dynamodb.CreateTableInput {
+	store := &Store{
+		hashKey: DefaultHashKey,
+	}
+
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	return &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{
+				AttributeName: aws.String(store.hashKey),
+				AttributeType: aws.String("S"),
+			},
+			{
+				AttributeName: aws.String(DefaultSnapshotRangeKey),
+				AttributeType: aws.String("N"),
+			},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{
+				AttributeName: aws.String(store.hashKey),
+				KeyType:       aws.String("HASH"),
+			},
+			{
+				AttributeName: aws.String(DefaultSnapshotRangeKey),
+				KeyType:       aws.String("RANGE"),
+			},
+		},
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(readCapacity),
+			WriteCapacityUnits: aws.Int64(writeCapacity),
+		},
+	}
+}
+
+// MakeCreateCheckpointTableInput is a utility tool to write the table definition for the table used by
+// CheckpointStore
+func MakeCreateCheckpointTableInput(tableName string, readCapacity, writeCapacity int64) *dynamodb.CreateTableInput {
+	return &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{
+				AttributeName: aws.String(DefaultCheckpointHashKey),
+				AttributeType: aws.String("S"),
+			},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{
+				AttributeName: aws.String(DefaultCheckpointHashKey),
+				KeyType:       aws.String("HASH"),
+			},
+		},
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(readCapacity),
+			WriteCapacityUnits: aws.Int64(writeCapacity),
+		},
+	}
+}
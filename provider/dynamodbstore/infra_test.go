@@ -20,3 +20,22 @@ func TestWithStreams(t *testing.T) {
 	assert.True(t, *input.StreamSpecification.StreamEnabled)
 	assert.Equal(t, "NEW_AND_OLD_IMAGES", *input.StreamSpecification.StreamViewType)
 }
+
+func TestMakeCreateLogTableInput(t *testing.T) {
+	input := dynamodbstore.MakeCreateLogTableInput("blah", 3, 3)
+	assert.Equal(t, "bucket", *input.KeySchema[0].AttributeName)
+	assert.Equal(t, "position", *input.KeySchema[1].AttributeName)
+}
+
+func TestMakeCreateSnapshotTableInput(t *testing.T) {
+	expected := "new-hash-key"
+	input := dynamodbstore.MakeCreateSnapshotTableInput("blah", 3, 3, dynamodbstore.WithHashKey(expected))
+	assert.Equal(t, expected, *input.KeySchema[0].AttributeName)
+	assert.Equal(t, dynamodbstore.DefaultSnapshotRangeKey, *input.KeySchema[1].AttributeName)
+}
+
+func TestMakeCreateCheckpointTableInput(t *testing.T) {
+	input := dynamodbstore.MakeCreateCheckpointTableInput("blah", 3, 3)
+	assert.Equal(t, dynamodbstore.DefaultCheckpointHashKey, *input.KeySchema[0].AttributeName)
+	assert.Len(t, input.KeySchema, 1)
+}
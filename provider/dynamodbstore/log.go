@@ -0,0 +1,351 @@
+package dynamodbstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/savaki/eventsource"
+)
+
+const (
+	// logBucketSize is the number of positions held under a single hash key of the log table
+	logBucketSize = 1000
+
+	// logCounterBucket is the bucket of the item holding the most recently allocated position
+	logCounterBucket = -1
+
+	// maxTransactItems is the maximum number of items DynamoDB permits within a single transaction
+	maxTransactItems = 100
+)
+
+const (
+	// DefaultLogAttempts is the default number of times a save is attempted while contending for the log counter; see
+	// WithLogRetry
+	DefaultLogAttempts = 10
+
+	// DefaultLogBackoff is the default delay prior to the first retry of a save contending for the log counter
+	DefaultLogBackoff = 10 * time.Millisecond
+
+	// DefaultLogMaxBackoff is the default upper bound of the delay between retries of a save contending for the log
+	// counter
+	DefaultLogMaxBackoff = time.Second
+)
+
+const (
+	// CodeLogContention is the code of the error returned when a save is unable to advance the log counter within
+	// the attempts permitted; see WithLogRetry
+	CodeLogContention = "LogContention"
+)
+
+var (
+	// ErrLogContention matches, using errors.Is, any error with CodeLogContention
+	ErrLogContention = eventsource.NewError(nil, CodeLogContention, "log contention")
+)
+
+const (
+	logBucket   = "bucket"
+	logPosition = "position"
+	logID       = "id"
+	logVersion  = "version"
+	logAt       = "at"
+	logType     = "type"
+	logData     = "data"
+	logMetadata = "metadata"
+	logCounter  = "counter"
+)
+
+var (
+	errLogNotEnabled = errors.New("log table not enabled; see WithLogTable")
+)
+
+// saveWithLog writes the event items, advances the counter item, and writes the log items within a single
+// transaction, so records are saved to the log if, and only if, they are saved to the aggregate.  The transaction is
+// conditional on the counter holding the position read prior to the transaction; as positions are allocated and
+// written together, positions are contiguous and a position is never visible before those preceding it.
+//
+// Every Save writes the counter item, which limits the write throughput of a Store using a log table to that of a
+// single item.  A save that loses the counter to a concurrent save is retried, after a jittered backoff, up to the
+// attempts specified by WithLogRetry; an error with CodeLogContention is returned once they are exhausted.
+func (s *Store) saveWithLog(ctx context.Context, aggregateID string, expectedVersion int, eventItems []*dynamodb.TransactWriteItem, records ...eventsource.Record) error {
+	if n := len(eventItems) + 1 + len(records); n > maxTransactItems {
+		return fmt.Errorf("unable to save %v records with log table, %v; a transaction is limited to %v items and requires %v", len(records), s.logTableName, maxTransactItems, n)
+	}
+
+	upgraded := false
+	for attempt := 1; ; attempt++ {
+		last, err := s.lastPosition(ctx)
+		if err != nil {
+			return err
+		}
+
+		items := make([]*dynamodb.TransactWriteItem, 0, len(eventItems)+1+len(records))
+		items = append(items, eventItems...)
+
+		items = append(items, &dynamodb.TransactWriteItem{
+			Update: &dynamodb.Update{
+				TableName: aws.String(s.logTableName),
+				Key: map[string]*dynamodb.AttributeValue{
+					logBucket:   {N: aws.String(strconv.Itoa(logCounterBucket))},
+					logPosition: {N: aws.String("0")},
+				},
+				ConditionExpression: aws.String("attribute_not_exists(#counter) OR #counter = :last"),
+				UpdateExpression:    aws.String("SET #counter = :next"),
+				ExpressionAttributeNames: map[string]*string{
+					"#counter": aws.String(logCounter),
+				},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":last": {N: aws.String(strconv.FormatInt(last, 10))},
+					":next": {N: aws.String(strconv.FormatInt(last+int64(len(records)), 10))},
+				},
+			},
+		})
+
+		for index, record := range records {
+			items = append(items, &dynamodb.TransactWriteItem{
+				Put: &dynamodb.Put{
+					TableName: aws.String(s.logTableName),
+					Item:      logItem(last+int64(index)+1, aggregateID, record),
+				},
+			})
+		}
+
+		_, err = s.api.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: items,
+		})
+		if err == nil {
+			return nil
+		}
+
+		v, ok := err.(*dynamodb.TransactionCanceledException)
+		if !ok {
+			return err
+		}
+
+		if conditionFailed(err, len(eventItems)) {
+			if !upgraded {
+				upgraded = true
+				ok, uerr := s.upgradeLegacyItems(ctx, eventItems)
+				if uerr != nil {
+					return uerr
+				}
+				if ok {
+					continue
+				}
+			}
+			return eventsource.NewError(err, eventsource.DuplicateVersion, "aggregate, %v, modified since version %v", aggregateID, expectedVersion)
+		}
+
+		retry := false
+		for _, reason := range v.CancellationReasons {
+			if code := aws.StringValue(reason.Code); code == "ConditionalCheckFailed" || code == "TransactionConflict" {
+				retry = true
+			}
+		}
+		if !retry {
+			return err
+		}
+
+		if attempt >= s.logAttempts {
+			return eventsource.NewError(err, CodeLogContention, "unable to advance the counter of log table, %v, for aggregate, %v, after %v attempts", s.logTableName, aggregateID, attempt)
+		}
+
+		if err := sleep(ctx, s.logDelay(attempt-1)); err != nil {
+			return err
+		}
+	}
+}
+
+// logDelay returns the delay prior to the specified retry, starting from 0, chosen uniformly between half the backoff
+// and the full backoff
+func (s *Store) logDelay(attempt int) time.Duration {
+	backoff := s.logBackoff
+	for i := 0; i < attempt && backoff < s.logMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > s.logMaxBackoff {
+		backoff = s.logMaxBackoff
+	}
+
+	if half := int64(backoff / 2); half > 0 {
+		return time.Duration(half + rand.Int63n(half+1))
+	}
+	return backoff
+}
+
+// sleep blocks for the duration provided; returns the error of ctx if it is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// logItem returns the item of the log table holding the record at the position provided
+func logItem(position int64, aggregateID string, record eventsource.Record) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{
+		logBucket:   {N: aws.String(strconv.FormatInt(position/logBucketSize, 10))},
+		logPosition: {N: aws.String(strconv.FormatInt(position, 10))},
+		logID:       {S: aws.String(aggregateID)},
+		logVersion:  {N: aws.String(strconv.Itoa(record.Version))},
+		logAt:       {N: aws.String(record.At.String())},
+		logData:     {B: record.Data},
+	}
+	if record.Type != "" {
+		item[logType] = &dynamodb.AttributeValue{S: aws.String(record.Type)}
+	}
+	if av := encodeMetadata(record.Metadata); av != nil {
+		item[logMetadata] = av
+	}
+	return item
+}
+
+// lastPosition returns the most recently allocated position within the log
+func (s *Store) lastPosition(ctx context.Context) (int64, error) {
+	out, err := s.api.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.logTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			logBucket:   {N: aws.String(strconv.Itoa(logCounterBucket))},
+			logPosition: {N: aws.String("0")},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	av, ok := out.Item[logCounter]
+	if !ok {
+		return 0, nil
+	}
+
+	return strconv.ParseInt(aws.StringValue(av.N), 10, 64)
+}
+
+// Read implements the eventsource.Reader interface using the table specified by WithLogTable.  Records are written to
+// the log in the same transaction as the counter is advanced, so positions are contiguous and a Read never skips over
+// a position that is yet to be written.
+func (s *Store) Read(ctx context.Context, startingPosition int64, recordCount int) ([]eventsource.StreamRecord, error) {
+	return s.read(ctx, startingPosition, recordCount, nil)
+}
+
+// ReadTypes implements the eventsource.TypeReader interface; see Read
+func (s *Store) ReadTypes(ctx context.Context, startingPosition int64, recordCount int, eventTypes ...string) ([]eventsource.StreamRecord, error) {
+	if len(eventTypes) == 0 {
+		return []eventsource.StreamRecord{}, nil
+	}
+
+	return s.read(ctx, startingPosition, recordCount, eventTypes)
+}
+
+// read reads records from the log table; when eventTypes is non-empty, only records of those types are returned
+func (s *Store) read(ctx context.Context, startingPosition int64, recordCount int, eventTypes []string) ([]eventsource.StreamRecord, error) {
+	if s.logTableName == "" {
+		return nil, errLogNotEnabled
+	}
+	if recordCount <= 0 {
+		return []eventsource.StreamRecord{}, nil
+	}
+
+	last, err := s.lastPosition(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]eventsource.StreamRecord, 0, recordCount)
+	for bucket := (startingPosition + 1) / logBucketSize; bucket <= last/logBucketSize && len(records) < recordCount; bucket++ {
+		input := &dynamodb.QueryInput{
+			TableName:              aws.String(s.logTableName),
+			ConsistentRead:         aws.Bool(true),
+			KeyConditionExpression: aws.String("#bucket = :bucket AND #position > :position"),
+			ExpressionAttributeNames: map[string]*string{
+				"#bucket":   aws.String(logBucket),
+				"#position": aws.String(logPosition),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":bucket":   {N: aws.String(strconv.FormatInt(bucket, 10))},
+				":position": {N: aws.String(strconv.FormatInt(startingPosition, 10))},
+			},
+		}
+
+		if len(eventTypes) > 0 {
+			refs := make([]string, 0, len(eventTypes))
+			for index, eventType := range eventTypes {
+				ref := ":type" + strconv.Itoa(index)
+				refs = append(refs, ref)
+				input.ExpressionAttributeValues[ref] = &dynamodb.AttributeValue{S: aws.String(eventType)}
+			}
+			input.ExpressionAttributeNames["#type"] = aws.String(logType)
+			input.FilterExpression = aws.String("#type IN (" + strings.Join(refs, ", ") + ")")
+		}
+
+		for len(records) < recordCount {
+			input.Limit = aws.Int64(int64(recordCount - len(records)))
+
+			out, err := s.api.QueryWithContext(ctx, input)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, item := range out.Items {
+				record, err := logRecord(item)
+				if err != nil {
+					return nil, err
+				}
+				records = append(records, record)
+			}
+
+			if len(out.LastEvaluatedKey) == 0 {
+				break
+			}
+			input.ExclusiveStartKey = out.LastEvaluatedKey
+		}
+	}
+
+	return records, nil
+}
+
+// logRecord converts an item from the log table into an eventsource.StreamRecord
+func logRecord(item map[string]*dynamodb.AttributeValue) (eventsource.StreamRecord, error) {
+	position, err := strconv.ParseInt(aws.StringValue(item[logPosition].N), 10, 64)
+	if err != nil {
+		return eventsource.StreamRecord{}, err
+	}
+
+	version, err := strconv.Atoi(aws.StringValue(item[logVersion].N))
+	if err != nil {
+		return eventsource.StreamRecord{}, err
+	}
+
+	at, err := strconv.ParseInt(aws.StringValue(item[logAt].N), 10, 64)
+	if err != nil {
+		return eventsource.StreamRecord{}, err
+	}
+
+	record := eventsource.StreamRecord{
+		Record: eventsource.Record{
+			Version:  version,
+			At:       eventsource.EpochMillis(at),
+			Data:     item[logData].B,
+			Metadata: decodeMetadata(item[logMetadata]),
+		},
+		AggregateID: aws.StringValue(item[logID].S),
+		Position:    position,
+	}
+	if v, ok := item[logType]; ok {
+		record.Type = aws.StringValue(v.S)
+	}
+
+	return record, nil
+}
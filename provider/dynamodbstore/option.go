@@ -2,8 +2,10 @@ package dynamodbstore
 
 import (
 	"io"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
 )
 
 type Option func(*Store)
@@ -43,6 +45,13 @@ func WithDynamoDB(api *dynamodb.DynamoDB) Option {
 	}
 }
 
+// WithDynamoDBStreams allows the caller to specify a pre-configured reference to DynamoDB Streams, used by Subscribe
+func WithDynamoDBStreams(api *dynamodbstreams.DynamoDBStreams) Option {
+	return func(s *Store) {
+		s.streams = api
+	}
+}
+
 // WithStreams is an option only used by the MakeCreateTableInput that indicates the table should be created with
 // DynamoDB streams enabled
 func WithStreams() Option {
@@ -51,6 +60,46 @@ func WithStreams() Option {
 	}
 }
 
+// WithLogTable specifies a table, created using MakeCreateLogTableInput, to which saved events are appended to provide
+// a globally ordered log of events; required to use Read.  Events are saved, and appended to the log, within a single
+// transaction that also advances a counter item.  As every Save writes the same counter item, write throughput is
+// limited to that of a single item and concurrent saves are retried as specified by WithLogRetry.
+//
+// A DynamoDB transaction holds at most 100 items.  A save writes one item per partition of events, the counter, and
+// one log item per record, so with the default of one event per item, a single save is limited to 49 records; larger
+// saves fail without writing anything.  Without a log table, a save is limited to 100 items.
+func WithLogTable(tableName string) Option {
+	return func(s *Store) {
+		s.logTableName = tableName
+	}
+}
+
+// WithLogRetry specifies the number of times a save is attempted while contending with concurrent saves for the
+// counter of the log table, along with the delay prior to the first retry, which doubles with each subsequent retry up
+// to maxBackoff.  Defaults to DefaultLogAttempts, DefaultLogBackoff, and DefaultLogMaxBackoff; values less than 1 are
+// ignored.
+func WithLogRetry(attempts int, backoff, maxBackoff time.Duration) Option {
+	return func(s *Store) {
+		if attempts > 0 {
+			s.logAttempts = attempts
+		}
+		if backoff > 0 {
+			s.logBackoff = backoff
+		}
+		if maxBackoff > 0 {
+			s.logMaxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithPollInterval specifies the interval at which Subscribe polls the table stream for new records; defaults to
+// DefaultPollInterval
+func WithPollInterval(interval time.Duration) Option {
+	return func(s *Store) {
+		s.pollInterval = interval
+	}
+}
+
 // WithDebug provides additional debugging information
 func WithDebug(w io.Writer) Option {
 	return func(s *Store) {
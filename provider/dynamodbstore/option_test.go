@@ -3,6 +3,7 @@ package dynamodbstore
 import (
 	"log"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -46,3 +47,22 @@ func TestWithRegion(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, expected, s.region)
 }
+
+func TestWithLogRetry(t *testing.T) {
+	s, err := New("blah", WithLogRetry(3, time.Millisecond, 4*time.Millisecond), WithDynamoDB(api))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, s.logAttempts)
+
+	for attempt, max := range []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond} {
+		delay := s.logDelay(attempt)
+		assert.True(t, delay >= max/2 && delay <= max, "attempt %v: %v", attempt, delay)
+	}
+
+	// Test - Non-positive values retain the defaults
+
+	s, err = New("blah", WithLogRetry(0, 0, 0), WithDynamoDB(api))
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultLogAttempts, s.logAttempts)
+	assert.Equal(t, DefaultLogBackoff, s.logBackoff)
+	assert.Equal(t, DefaultLogMaxBackoff, s.logMaxBackoff)
+}
@@ -0,0 +1,108 @@
+package dynamodbstore
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/savaki/eventsource"
+)
+
+const (
+	// DefaultSnapshotRangeKey is the range key (sort key) of the snapshot table
+	DefaultSnapshotRangeKey = "version"
+)
+
+const (
+	snapshotAt   = "at"
+	snapshotData = "data"
+)
+
+// SnapshotStore represents a dynamodb backed eventsource.SnapshotStore
+type SnapshotStore struct {
+	tableName string
+	hashKey   string
+	rangeKey  string
+	api       *dynamodb.DynamoDB
+}
+
+// Save implements the eventsource.SnapshotStore interface
+func (s *SnapshotStore) Save(ctx context.Context, snapshot eventsource.Snapshot) error {
+	_, err := s.api.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			s.hashKey:    {S: aws.String(snapshot.AggregateID)},
+			s.rangeKey:   {N: aws.String(strconv.Itoa(snapshot.Version))},
+			snapshotAt:   {N: aws.String(snapshot.At.String())},
+			snapshotData: {B: snapshot.Data},
+		},
+	})
+	return err
+}
+
+// Fetch implements the eventsource.SnapshotStore interface
+func (s *SnapshotStore) Fetch(ctx context.Context, aggregateID string, version int) (eventsource.Snapshot, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(s.tableName),
+		ConsistentRead:         aws.Bool(true),
+		ScanIndexForward:       aws.Bool(false),
+		Limit:                  aws.Int64(1),
+		KeyConditionExpression: aws.String("#key = :key"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key": aws.String(s.hashKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":key": {S: aws.String(aggregateID)},
+		},
+	}
+
+	if version > 0 {
+		input.KeyConditionExpression = aws.String("#key = :key AND #version <= :version")
+		input.ExpressionAttributeNames["#version"] = aws.String(s.rangeKey)
+		input.ExpressionAttributeValues[":version"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(version))}
+	}
+
+	out, err := s.api.QueryWithContext(ctx, input)
+	if err != nil {
+		return eventsource.Snapshot{}, err
+	}
+
+	if len(out.Items) == 0 {
+		return eventsource.Snapshot{}, eventsource.NewError(nil, eventsource.SnapshotNotFound, "no snapshot found for aggregate id, %v", aggregateID)
+	}
+
+	item := out.Items[0]
+	snapshotVersion, err := strconv.Atoi(aws.StringValue(item[s.rangeKey].N))
+	if err != nil {
+		return eventsource.Snapshot{}, err
+	}
+
+	at, err := strconv.ParseInt(aws.StringValue(item[snapshotAt].N), 10, 64)
+	if err != nil {
+		return eventsource.Snapshot{}, err
+	}
+
+	return eventsource.Snapshot{
+		AggregateID: aggregateID,
+		Version:     snapshotVersion,
+		At:          eventsource.EpochMillis(at),
+		Data:        item[snapshotData].B,
+	}, nil
+}
+
+// NewSnapshotStore returns a SnapshotStore backed by the table provided; see MakeCreateSnapshotTableInput.  Accepts
+// the same options as New, although only WithRegion, WithHashKey, and WithDynamoDB apply.
+func NewSnapshotStore(tableName string, opts ...Option) (*SnapshotStore, error) {
+	store, err := New(tableName, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SnapshotStore{
+		tableName: tableName,
+		hashKey:   store.hashKey,
+		rangeKey:  DefaultSnapshotRangeKey,
+		api:       store.api,
+	}, nil
+}
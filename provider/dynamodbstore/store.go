@@ -3,7 +3,7 @@ package dynamodbstore
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"sort"
@@ -15,7 +15,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/pkg/errors"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
 	"github.com/savaki/eventsource"
 )
 
@@ -31,6 +31,12 @@ const (
 
 	// atBase refers to the base encoding for the record at
 	atBase = 36
+
+	// metadataPrefix prefixes the version to form the key of the event metadata in the dynamodb item
+	metadataPrefix = "m"
+
+	// typePrefix prefixes the version to form the key of the event type in the dynamodb item
+	typePrefix = "t"
 )
 
 var (
@@ -49,39 +55,65 @@ type Store struct {
 	rangeKey      string
 	api           *dynamodb.DynamoDB
 	useStreams    bool
+	logTableName  string
 	eventsPerItem int
+	streams       *dynamodbstreams.DynamoDBStreams
+	pollInterval  time.Duration
+	logAttempts   int
+	logBackoff    time.Duration
+	logMaxBackoff time.Duration
 	debug         bool
 	writer        io.Writer
 }
 
 // Save implements the eventsource.Store interface
 func (s *Store) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
-	inputs, err := makeUpdateItemInput(s.tableName, s.hashKey, s.rangeKey, s.eventsPerItem, aggregateID, records...)
-	if err != nil {
-		return err
+	if len(records) == 0 {
+		return nil
 	}
 
-	for _, input := range inputs {
-		if s.debug {
-			encoder := json.NewEncoder(s.writer)
-			encoder.SetIndent("", "  ")
-			encoder.Encode(input)
+	expectedVersion := records[0].Version
+	for _, record := range records[1:] {
+		if record.Version < expectedVersion {
+			expectedVersion = record.Version
 		}
+	}
 
-		_, err := s.api.UpdateItem(input)
-		if err != nil {
-			if v, ok := err.(awserr.Error); ok {
-				return errors.Wrapf(err, "Save failed. %v [%v]", v.Message(), v.Code())
-			}
-			return err
+	return s.SaveVersion(ctx, aggregateID, expectedVersion-1, records...)
+}
+
+// SaveVersion implements the eventsource.VersionSaver interface.  Each item records the most recent version written
+// to it, so conflicts are detected against the items the records would be written to, along with the item holding the
+// version following expectedVersion.  The items are written within a single transaction; see WithLogTable for the
+// resulting limit on the number of records saved at once.
+func (s *Store) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...eventsource.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	versions := make(map[int]struct{}, len(records))
+	for _, record := range records {
+		if _, ok := versions[record.Version]; ok {
+			return eventsource.NewError(nil, eventsource.DuplicateVersion, "records for aggregate, %v, contain version %v more than once", aggregateID, record.Version)
 		}
+		versions[record.Version] = struct{}{}
+	}
+
+	inputs, err := makeUpdateItemInput(s.tableName, s.hashKey, s.rangeKey, s.eventsPerItem, aggregateID, expectedVersion, records...)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	items := s.transactItems(aggregateID, expectedVersion, inputs)
+	if s.logTableName != "" {
+		return s.saveWithLog(ctx, aggregateID, expectedVersion, items, records...)
+	}
+
+	return s.saveItems(ctx, aggregateID, expectedVersion, items)
 }
 
 func (s *Store) logf(format string, args ...interface{}) {
-	if s.debug {
+	if !s.debug {
 		return
 	}
 
@@ -101,11 +133,43 @@ func (s *Store) Fetch(ctx context.Context, aggregateID string, version int) (eve
 		return eventsource.History{}, err
 	}
 
-	history := make(eventsource.History, 0, version)
+	history, err := s.query(ctx, input, func(recordVersion int) bool {
+		return version == 0 || recordVersion <= version
+	})
+	if err != nil {
+		return eventsource.History{}, err
+	}
+
+	if len(history) == 0 {
+		return eventsource.History{}, eventsource.NewError(nil, eventsource.AggregateNotFound, "no aggregate found with id, %v", aggregateID)
+	}
+
+	return history, nil
+}
+
+// FetchAfter implements the eventsource.AfterFetcher interface
+func (s *Store) FetchAfter(ctx context.Context, aggregateID string, version int) (eventsource.History, error) {
+	input, err := makeQueryInput(s.tableName, s.hashKey, s.rangeKey, aggregateID, 0)
+	if err != nil {
+		return eventsource.History{}, err
+	}
+
+	partition := selectPartition(version+1, s.eventsPerItem)
+	input.KeyConditionExpression = aws.String("#key = :key AND #partition >= :partition")
+	input.ExpressionAttributeNames["#partition"] = aws.String(s.rangeKey)
+	input.ExpressionAttributeValues[":partition"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(partition))}
+
+	return s.query(ctx, input, func(recordVersion int) bool {
+		return recordVersion > version
+	})
+}
+
+// query executes the query and returns the events contained in the items returned for which accept returns true
+func (s *Store) query(ctx context.Context, input *dynamodb.QueryInput, accept func(version int) bool) (eventsource.History, error) {
+	history := eventsource.History{}
 
-	var startKey map[string]*dynamodb.AttributeValue
 	for {
-		out, err := s.api.Query(input)
+		out, err := s.api.QueryWithContext(ctx, input)
 		if err != nil {
 			return eventsource.History{}, err
 		}
@@ -114,7 +178,8 @@ func (s *Store) Fetch(ctx context.Context, aggregateID string, version int) (eve
 			break
 		}
 
-		// events are stored within av as _t{version} = {event-type}, _d{version} = {serialized event}
+		// events are stored within av as _{version}:{at} = {serialized event}, t{version} = {event type},
+		// m{version} = {metadata}
 		for _, item := range out.Items {
 			for key, av := range item {
 				if !IsKey(key) {
@@ -126,22 +191,28 @@ func (s *Store) Fetch(ctx context.Context, aggregateID string, version int) (eve
 					return nil, err
 				}
 
-				if version > 0 && recordVersion > version {
+				if !accept(recordVersion) {
 					continue
 				}
 
-				history = append(history, eventsource.Record{
-					Version: recordVersion,
-					At:      recordAt,
-					Data:    av.B,
-				})
+				record := eventsource.Record{
+					Version:  recordVersion,
+					At:       recordAt,
+					Data:     av.B,
+					Metadata: decodeMetadata(item[metadataPrefix+strconv.Itoa(recordVersion)]),
+				}
+				if v, ok := item[typePrefix+strconv.Itoa(recordVersion)]; ok {
+					record.Type = aws.StringValue(v.S)
+				}
+
+				history = append(history, record)
 			}
 		}
 
-		startKey = out.LastEvaluatedKey
-		if len(startKey) == 0 {
+		if len(out.LastEvaluatedKey) == 0 {
 			break
 		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
 	}
 
 	sort.Slice(history, func(i, j int) bool {
@@ -158,6 +229,10 @@ func New(tableName string, opts ...Option) (*Store, error) {
 		hashKey:       DefaultHashKey,
 		rangeKey:      DefaultRangeKey,
 		eventsPerItem: 1,
+		pollInterval:  DefaultPollInterval,
+		logAttempts:   DefaultLogAttempts,
+		logBackoff:    DefaultLogBackoff,
+		logMaxBackoff: DefaultLogMaxBackoff,
 	}
 
 	for _, opt := range opts {
@@ -169,13 +244,21 @@ func New(tableName string, opts ...Option) (*Store, error) {
 		s, err := session.NewSession(cfg)
 		if err != nil {
 			if v, ok := err.(awserr.Error); ok {
-				return nil, errors.Wrapf(err, "Unable to create AWS Session - %v [%v]", v.Message(), v.Code())
+				return nil, fmt.Errorf("Unable to create AWS Session - %v [%v]: %w", v.Message(), v.Code(), err)
 			}
 			return nil, err
 		}
 		store.api = dynamodb.New(s)
 	}
 
+	if store.streams == nil {
+		s, err := session.NewSession(store.api.Config.Copy())
+		if err != nil {
+			return nil, err
+		}
+		store.streams = dynamodbstreams.New(s)
+	}
+
 	return store, nil
 }
 
@@ -195,7 +278,9 @@ func partition(eventsPerItem int, records ...eventsource.Record) (map[int][]even
 	return partitions, nil
 }
 
-func makeUpdateItemInput(tableName, hashKey, rangeKey string, eventsPerItem int, aggregateID string, records ...eventsource.Record) ([]*dynamodb.UpdateItemInput, error) {
+// makeUpdateItemInput
+//  - expectedVersion - the update is conditional on each item not containing a version newer than this
+func makeUpdateItemInput(tableName, hashKey, rangeKey string, eventsPerItem int, aggregateID string, expectedVersion int, records ...eventsource.Record) ([]*dynamodb.UpdateItemInput, error) {
 	eventCount := len(records)
 	partitions, err := partition(eventsPerItem, records...)
 	if err != nil {
@@ -211,20 +296,34 @@ func makeUpdateItemInput(tableName, hashKey, rangeKey string, eventsPerItem int,
 				rangeKey: {N: aws.String(strconv.Itoa(partitionID))},
 			},
 			ExpressionAttributeNames: map[string]*string{
-				"#revision": aws.String("revision"),
+				"#revision": aws.String(itemRevision),
+				"#version":  aws.String(itemVersion),
 			},
 			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-				":one": {N: aws.String("1")},
+				":one":      {N: aws.String("1")},
+				":expected": {N: aws.String(strconv.Itoa(expectedVersion))},
 			},
 		}
 
+		// Each item tracks the most recent version it contains, which allows the update to be rejected when the item
+		// has been modified after the expected version
+
+		maxVersion := partition[0].Version
+		for _, record := range partition {
+			if record.Version > maxVersion {
+				maxVersion = record.Version
+			}
+		}
+		input.ExpressionAttributeValues[":version"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(maxVersion))}
+
 		// Add each element within the partition to the UpdateItemInput
 
 		condExpr := &bytes.Buffer{}
 		updateExpr := &bytes.Buffer{}
-		io.WriteString(updateExpr, "ADD #revision :one SET ")
+		io.WriteString(condExpr, conditionNotModified)
+		io.WriteString(updateExpr, "ADD #revision :one SET #version = :version")
 
-		for index, record := range partition {
+		for _, record := range partition {
 			version := strconv.Itoa(record.Version)
 			at := strconv.FormatInt(record.At.Int64(), atBase)
 
@@ -234,14 +333,28 @@ func makeUpdateItemInput(tableName, hashKey, rangeKey string, eventsPerItem int,
 			nameRef := "#" + prefix + version
 			valueRef := ":" + prefix + version
 
-			if index > 0 {
-				io.WriteString(condExpr, " AND ")
-				io.WriteString(updateExpr, ", ")
-			}
-			fmt.Fprintf(condExpr, "attribute_not_exists(%v)", nameRef)
-			fmt.Fprintf(updateExpr, "%v = %v", nameRef, valueRef)
+			fmt.Fprintf(condExpr, " AND attribute_not_exists(%v)", nameRef)
+			fmt.Fprintf(updateExpr, ", %v = %v", nameRef, valueRef)
 			input.ExpressionAttributeNames[nameRef] = aws.String(key)
 			input.ExpressionAttributeValues[valueRef] = &dynamodb.AttributeValue{B: record.Data}
+
+			if record.Type != "" {
+				typeNameRef := "#" + typePrefix + version
+				typeValueRef := ":" + typePrefix + version
+
+				fmt.Fprintf(updateExpr, ", %v = %v", typeNameRef, typeValueRef)
+				input.ExpressionAttributeNames[typeNameRef] = aws.String(typePrefix + version)
+				input.ExpressionAttributeValues[typeValueRef] = &dynamodb.AttributeValue{S: aws.String(record.Type)}
+			}
+
+			if av := encodeMetadata(record.Metadata); av != nil {
+				metadataNameRef := "#" + metadataPrefix + version
+				metadataValueRef := ":" + metadataPrefix + version
+
+				fmt.Fprintf(updateExpr, ", %v = %v", metadataNameRef, metadataValueRef)
+				input.ExpressionAttributeNames[metadataNameRef] = aws.String(metadataPrefix + version)
+				input.ExpressionAttributeValues[metadataValueRef] = av
+			}
 		}
 
 		input.ConditionExpression = aws.String(condExpr.String())
@@ -280,6 +393,32 @@ func makeQueryInput(tableName, hashKey, rangeKey string, aggregateID string, par
 	return input, nil
 }
 
+// encodeMetadata converts the record metadata into a map attribute; nil if there is no metadata
+func encodeMetadata(metadata map[string]string) *dynamodb.AttributeValue {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	m := make(map[string]*dynamodb.AttributeValue, len(metadata))
+	for k, v := range metadata {
+		m[k] = &dynamodb.AttributeValue{S: aws.String(v)}
+	}
+	return &dynamodb.AttributeValue{M: m}
+}
+
+// decodeMetadata converts a map attribute created by encodeMetadata back into record metadata
+func decodeMetadata(av *dynamodb.AttributeValue) map[string]string {
+	if av == nil || len(av.M) == 0 {
+		return nil
+	}
+
+	metadata := make(map[string]string, len(av.M))
+	for k, v := range av.M {
+		metadata[k] = aws.StringValue(v.S)
+	}
+	return metadata
+}
+
 func selectPartition(version, eventsPerItem int) int {
 	return version / eventsPerItem
 }
@@ -2,10 +2,13 @@ package dynamodbstore_test
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -16,6 +19,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/savaki/eventsource"
 	"github.com/savaki/eventsource/provider/dynamodbstore"
+	"github.com/savaki/eventsource/storetest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -261,3 +265,325 @@ func TestStore_Fetch(t *testing.T) {
 	assert.Equal(t, 1, history[0].Version)
 	assert.Equal(t, r1, history[0])
 }
+
+func TestStore_SaveVersion(t *testing.T) {
+	tableName := "sample_events"
+
+	for _, eventsPerItem := range []int{1, 3} {
+		t.Run(strconv.Itoa(eventsPerItem), func(t *testing.T) {
+			aggregateID := strconv.FormatInt(time.Now().UnixNano(), 10)
+			r1 := eventsource.Record{Version: 1, At: 1, Data: []byte("a")}
+
+			store, err := dynamodbstore.New(tableName,
+				dynamodbstore.WithDynamoDB(api),
+				dynamodbstore.WithEventPerItem(eventsPerItem),
+			)
+			assert.Nil(t, err)
+
+			ctx := context.Background()
+			err = store.SaveVersion(ctx, aggregateID, 0, r1)
+			assert.Nil(t, err)
+
+			// Test - Saving against a stale version should fail with DuplicateVersion
+
+			err = store.SaveVersion(ctx, aggregateID, 0, eventsource.Record{Version: 1, At: 3, Data: []byte("c")})
+			assert.NotNil(t, err)
+			v, ok := err.(eventsource.Error)
+			assert.True(t, ok)
+			assert.Equal(t, eventsource.DuplicateVersion, v.Code())
+
+			// Test - Saving a duplicate version should fail with DuplicateVersion
+
+			err = store.Save(ctx, aggregateID, r1)
+			assert.NotNil(t, err)
+
+			// Test - Saving against a stale version should fail even if the versions do not collide
+
+			err = store.SaveVersion(ctx, aggregateID, 0, eventsource.Record{Version: 2, At: 3, Data: []byte("c")})
+			assert.True(t, errors.Is(err, eventsource.ErrDuplicateVersion))
+
+			// Test - A conflict on any item saves none of the records
+
+			r2 := eventsource.Record{Version: 2, At: 2, Data: []byte("b")}
+			r4 := eventsource.Record{Version: 4, At: 4, Data: []byte("d")}
+			assert.Nil(t, store.SaveVersion(ctx, aggregateID, 1, r2))
+			err = store.SaveVersion(ctx, aggregateID, 1, eventsource.Record{Version: 3, At: 3, Data: []byte("c")}, r4)
+			assert.True(t, errors.Is(err, eventsource.ErrDuplicateVersion))
+
+			history, err := store.Fetch(ctx, aggregateID, 0)
+			assert.Nil(t, err)
+			assert.Equal(t, eventsource.History{r1, r2}, history)
+		})
+	}
+}
+
+func TestStore_SaveVersionLegacy(t *testing.T) {
+	tableName := "sample_events"
+	aggregateID := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	store, err := dynamodbstore.New(tableName,
+		dynamodbstore.WithDynamoDB(api),
+		dynamodbstore.WithEventPerItem(3),
+	)
+	assert.Nil(t, err)
+
+	// Items written before versions were tracked hold a revision, but no version
+
+	_, err = api.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			dynamodbstore.DefaultHashKey:  {S: aws.String(aggregateID)},
+			dynamodbstore.DefaultRangeKey: {N: aws.String("0")},
+			"revision":                    {N: aws.String("1")},
+			"_1:1":                        {B: []byte("a")},
+		},
+	})
+	assert.Nil(t, err)
+
+	// Test - Legacy items are checked against the versions they contain
+
+	ctx := context.Background()
+	err = store.SaveVersion(ctx, aggregateID, 0, eventsource.Record{Version: 2, At: 2, Data: []byte("b")})
+	assert.True(t, errors.Is(err, eventsource.ErrDuplicateVersion))
+
+	err = store.SaveVersion(ctx, aggregateID, 1, eventsource.Record{Version: 2, At: 2, Data: []byte("b")})
+	assert.Nil(t, err)
+
+	history, err := store.Fetch(ctx, aggregateID, 0)
+	assert.Nil(t, err)
+	assert.Len(t, history, 2)
+}
+
+func TestStore_Read(t *testing.T) {
+	tableName := "sample_events"
+	logTableName := "sample_log"
+	_, err := api.CreateTable(dynamodbstore.MakeCreateLogTableInput(logTableName, 10, 10))
+	if err != nil {
+		v, ok := err.(awserr.Error)
+		assert.True(t, ok && v.Code() == "ResourceInUseException")
+	}
+
+	store, err := dynamodbstore.New(tableName,
+		dynamodbstore.WithDynamoDB(api),
+		dynamodbstore.WithLogTable(logTableName),
+	)
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	aggregateID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	r1 := eventsource.Record{Version: 1, At: 1, Data: []byte("a")}
+	r2 := eventsource.Record{Version: 2, At: 2, Data: []byte("b")}
+	err = store.Save(ctx, aggregateID, r1, r2)
+	assert.Nil(t, err)
+
+	// Test - Read the log until our records are found
+
+	var found []eventsource.StreamRecord
+	var position int64
+	for {
+		records, err := store.Read(ctx, position, 100)
+		assert.Nil(t, err)
+		if len(records) == 0 {
+			break
+		}
+
+		for _, record := range records {
+			assert.True(t, record.Position > position)
+			position = record.Position
+			if record.AggregateID == aggregateID {
+				found = append(found, record)
+			}
+		}
+	}
+
+	assert.Len(t, found, 2)
+	assert.Equal(t, r1, found[0].Record)
+	assert.Equal(t, r2, found[1].Record)
+}
+
+func TestStore_ReadConcurrent(t *testing.T) {
+	tableName := "sample_events"
+	logTableName := "sample_log"
+	_, err := api.CreateTable(dynamodbstore.MakeCreateLogTableInput(logTableName, 10, 10))
+	if err != nil {
+		v, ok := err.(awserr.Error)
+		assert.True(t, ok && v.Code() == "ResourceInUseException")
+	}
+
+	store, err := dynamodbstore.New(tableName,
+		dynamodbstore.WithDynamoDB(api),
+		dynamodbstore.WithLogTable(logTableName),
+	)
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	prefix := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	// Test - Concurrent saves each allocate contiguous positions in the log
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(aggregateID string) {
+			defer wg.Done()
+			err := store.Save(ctx, aggregateID,
+				eventsource.Record{Version: 1, At: 1, Data: []byte("a")},
+				eventsource.Record{Version: 2, At: 2, Data: []byte("b")},
+			)
+			assert.Nil(t, err)
+		}(prefix + "-" + strconv.Itoa(i))
+	}
+	wg.Wait()
+
+	var found int
+	var position int64
+	for {
+		records, err := store.Read(ctx, position, 100)
+		assert.Nil(t, err)
+		if len(records) == 0 {
+			break
+		}
+
+		for _, record := range records {
+			assert.Equal(t, position+1, record.Position)
+			position = record.Position
+			if strings.HasPrefix(record.AggregateID, prefix+"-") {
+				found++
+			}
+		}
+	}
+	assert.Equal(t, 10, found)
+}
+
+func TestSnapshotStore(t *testing.T) {
+	tableName := "sample_snapshots"
+	_, err := api.CreateTable(dynamodbstore.MakeCreateSnapshotTableInput(tableName, 10, 10))
+	if err != nil {
+		v, ok := err.(awserr.Error)
+		assert.True(t, ok && v.Code() == "ResourceInUseException")
+	}
+
+	store, err := dynamodbstore.NewSnapshotStore(tableName, dynamodbstore.WithDynamoDB(api))
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	aggregateID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	_, err = store.Fetch(ctx, aggregateID, 0)
+	assert.NotNil(t, err)
+
+	s1 := eventsource.Snapshot{AggregateID: aggregateID, Version: 1, At: 1, Data: []byte("a")}
+	s2 := eventsource.Snapshot{AggregateID: aggregateID, Version: 3, At: 2, Data: []byte("b")}
+	assert.Nil(t, store.Save(ctx, s1))
+	assert.Nil(t, store.Save(ctx, s2))
+
+	found, err := store.Fetch(ctx, aggregateID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, s2, found)
+
+	found, err = store.Fetch(ctx, aggregateID, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, s1, found)
+}
+
+func TestStore_Metadata(t *testing.T) {
+	tableName := "sample_events"
+
+	store, err := dynamodbstore.New(tableName,
+		dynamodbstore.WithDynamoDB(api),
+		dynamodbstore.WithEventPerItem(2),
+	)
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	aggregateID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	r1 := eventsource.Record{Version: 1, At: 1, Data: []byte("a"), Metadata: map[string]string{eventsource.MetadataActor: "joe"}}
+	r2 := eventsource.Record{Version: 2, At: 2, Data: []byte("b")}
+	err = store.Save(ctx, aggregateID, r1, r2)
+	assert.Nil(t, err)
+
+	history, err := store.Fetch(ctx, aggregateID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, eventsource.History{r1, r2}, history)
+}
+
+func TestStore_Conformance(t *testing.T) {
+	tableName := "sample_events"
+	logTableName := "sample_log"
+
+	for _, eventsPerItem := range []int{1, 3} {
+		t.Run(strconv.Itoa(eventsPerItem), func(t *testing.T) {
+			storetest.TestStore(t, func(t *testing.T) eventsource.Store {
+				store, err := dynamodbstore.New(tableName,
+					dynamodbstore.WithDynamoDB(api),
+					dynamodbstore.WithEventPerItem(eventsPerItem),
+					dynamodbstore.WithLogTable(logTableName),
+				)
+				assert.Nil(t, err)
+				return store
+			})
+		})
+	}
+}
+
+func TestCheckpointStore(t *testing.T) {
+	tableName := "sample_checkpoints"
+	_, err := api.CreateTable(dynamodbstore.MakeCreateCheckpointTableInput(tableName, 10, 10))
+	if err != nil {
+		v, ok := err.(awserr.Error)
+		assert.True(t, ok && v.Code() == "ResourceInUseException")
+	}
+
+	store, err := dynamodbstore.NewCheckpointStore(tableName, dynamodbstore.WithDynamoDB(api))
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	name := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	position, err := store.Load(ctx, name)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), position)
+
+	assert.Nil(t, store.Save(ctx, name, 123))
+
+	position, err = store.Load(ctx, name)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(123), position)
+}
+
+func TestStore_Subscribe(t *testing.T) {
+	tableName := "subscribe_events"
+	_, err := api.CreateTable(dynamodbstore.MakeCreateTableInput(tableName, 10, 10, dynamodbstore.WithStreams()))
+	if err != nil {
+		v, ok := err.(awserr.Error)
+		assert.True(t, ok && v.Code() == "ResourceInUseException")
+	}
+
+	store, err := dynamodbstore.New(tableName,
+		dynamodbstore.WithDynamoDB(api),
+		dynamodbstore.WithPollInterval(50*time.Millisecond),
+	)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	aggregateID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	r1 := eventsource.Record{Version: 1, At: 1, Type: "Created", Data: []byte("a")}
+	r2 := eventsource.Record{Version: 2, At: 2, Type: "Renamed", Data: []byte("b")}
+
+	ch := store.Subscribe(ctx, eventsource.FilterAggregateID(aggregateID), eventsource.FilterEventType("Renamed"))
+	err = store.Save(ctx, aggregateID, r1, r2)
+	assert.Nil(t, err)
+
+	select {
+	case record := <-ch:
+		assert.Equal(t, r2, record.Record)
+		assert.Equal(t, aggregateID, record.AggregateID)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for record")
+	}
+
+	cancel()
+	for range ch {
+	}
+}
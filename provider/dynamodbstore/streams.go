@@ -3,9 +3,12 @@ package dynamodbstore
 import (
 	"errors"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/apex/go-apex/dynamo"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/savaki/eventsource"
 )
 
 type event struct {
@@ -15,32 +18,12 @@ type event struct {
 
 // Changes returns an ordered list of changes from the *dynamo.Record; will never return nil
 func RawEvents(record *dynamo.Record) ([][]byte, error) {
-	keys := map[string]struct{}{}
-
-	// determine which keys are new
-
-	if record != nil && record.Dynamodb != nil {
-		if record.Dynamodb.NewImage != nil {
-			for k := range record.Dynamodb.NewImage {
-				if IsKey(k) {
-					keys[k] = struct{}{}
-				}
-			}
-		}
-
-		if record.Dynamodb.OldImage != nil {
-			for k := range record.Dynamodb.OldImage {
-				if IsKey(k) {
-					delete(keys, k)
-				}
-			}
-		}
-	}
+	keys := newKeys(record)
 
 	// using those keys, construct a sorted list of items
 
 	items := make([]event, 0, len(keys))
-	for key := range keys {
+	for _, key := range keys {
 		version, _, err := VersionAndAt(key)
 		if err != nil {
 			return nil, err
@@ -66,6 +49,70 @@ func RawEvents(record *dynamo.Record) ([][]byte, error) {
 	return events, nil
 }
 
+// StreamRecords returns the records added to the item by the *dynamo.Record in version order; will never return nil.
+// DynamoDB stream sequence numbers do not fit within an int64, so the Position of each record is left unset.
+func (s *Store) StreamRecords(record *dynamo.Record) ([]eventsource.StreamRecord, error) {
+	keys := newKeys(record)
+	if len(keys) == 0 {
+		return []eventsource.StreamRecord{}, nil
+	}
+
+	image := record.Dynamodb.NewImage
+	aggregateID := ""
+	if v, ok := image[s.hashKey]; ok {
+		aggregateID = aws.StringValue(v.S)
+	}
+
+	records := make([]eventsource.StreamRecord, 0, len(keys))
+	for _, key := range keys {
+		version, at, err := VersionAndAt(key)
+		if err != nil {
+			return nil, err
+		}
+
+		streamRecord := eventsource.StreamRecord{
+			Record: eventsource.Record{
+				Version:  version,
+				At:       at,
+				Data:     image[key].B,
+				Metadata: decodeMetadata(image[metadataPrefix+strconv.Itoa(version)]),
+			},
+			AggregateID: aggregateID,
+		}
+		if v, ok := image[typePrefix+strconv.Itoa(version)]; ok {
+			streamRecord.Type = aws.StringValue(v.S)
+		}
+
+		records = append(records, streamRecord)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Version < records[j].Version
+	})
+
+	return records, nil
+}
+
+// newKeys returns the event keys present in the new image of the *dynamo.Record, but not the old
+func newKeys(record *dynamo.Record) []string {
+	if record == nil || record.Dynamodb == nil {
+		return nil
+	}
+
+	keys := []string{}
+	for k := range record.Dynamodb.NewImage {
+		if !IsKey(k) {
+			continue
+		}
+		if _, ok := record.Dynamodb.OldImage[k]; ok {
+			continue
+		}
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
 var (
 	errInvalidEventSource = errors.New("invalid event source arn")
 )
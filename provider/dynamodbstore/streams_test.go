@@ -4,7 +4,9 @@ import (
 	"testing"
 
 	"github.com/apex/go-apex/dynamo"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/savaki/eventsource"
 	"github.com/savaki/eventsource/provider/dynamodbstore"
 	"github.com/stretchr/testify/assert"
 )
@@ -37,6 +39,50 @@ func TestRawEvents(t *testing.T) {
 	assert.Equal(t, d, events[2])
 }
 
+func TestStore_StreamRecords(t *testing.T) {
+	store, err := dynamodbstore.New("blah", dynamodbstore.WithHashKey("id"))
+	assert.Nil(t, err)
+
+	record := &dynamo.Record{
+		Dynamodb: &dynamo.StreamRecord{
+			NewImage: map[string]*dynamodb.AttributeValue{
+				"id":   {S: aws.String("abc")},
+				"_2:2": {B: []byte("b")},
+				"t2":   {S: aws.String("Renamed")},
+				"m2":   {M: map[string]*dynamodb.AttributeValue{"actor": {S: aws.String("joe")}}},
+				"_1:1": {B: []byte("a")},
+				"t1":   {S: aws.String("Created")},
+			},
+			OldImage: map[string]*dynamodb.AttributeValue{
+				"id":   {S: aws.String("abc")},
+				"_1:1": {B: []byte("a")},
+				"t1":   {S: aws.String("Created")},
+			},
+		},
+	}
+
+	records, err := store.StreamRecords(record)
+	assert.Nil(t, err)
+	assert.Equal(t, []eventsource.StreamRecord{
+		{
+			Record: eventsource.Record{
+				Version:  2,
+				At:       2,
+				Type:     "Renamed",
+				Data:     []byte("b"),
+				Metadata: map[string]string{"actor": "joe"},
+			},
+			AggregateID: "abc",
+		},
+	}, records)
+
+	// Test - Records without new events return an empty slice
+
+	records, err = store.StreamRecords(&dynamo.Record{})
+	assert.Nil(t, err)
+	assert.Len(t, records, 0)
+}
+
 func TestTableName(t *testing.T) {
 	arn := "arn:aws:dynamodb:us-west-2:528688496454:table/table-local-orgs/stream/2017-03-14T04:49:34.930"
 	tableName, err := dynamodbstore.TableName(arn)
@@ -0,0 +1,223 @@
+package dynamodbstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/apex/go-apex/dynamo"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/savaki/eventsource"
+)
+
+const (
+	// DefaultPollInterval is the default interval at which Subscribe polls the table stream for new records
+	DefaultPollInterval = time.Second
+)
+
+// shard tracks the position of Subscribe within a single shard of the table stream
+type shard struct {
+	id       string
+	parentID string
+	iterator *string
+}
+
+// subscription holds the state of a single call to Subscribe
+type subscription struct {
+	streamArn string
+	shards    []*shard
+	seen      map[string]struct{}
+	started   bool
+}
+
+// Subscribe implements the eventsource.Subscriber interface by polling the DynamoDB stream of the table, which must be
+// created with streams enabled; see WithStreams.  Records are decoded using StreamRecords and, as such, have no
+// Position.  Records for a given aggregate are delivered in version order; records of different aggregates may be
+// interleaved arbitrarily.  Errors are logged and the request retried after the poll interval.
+func (s *Store) Subscribe(ctx context.Context, filters ...eventsource.Filter) <-chan eventsource.StreamRecord {
+	ch := make(chan eventsource.StreamRecord)
+
+	sub := &subscription{seen: map[string]struct{}{}}
+	s.refreshShards(ctx, sub)
+
+	go func() {
+		defer close(ch)
+
+		for {
+			open := make(map[string]struct{}, len(sub.shards))
+			for _, sh := range sub.shards {
+				open[sh.id] = struct{}{}
+			}
+
+			remaining := sub.shards[:0]
+			for _, sh := range sub.shards {
+				// children are not read until their parent has been read to the end, preserving version order
+
+				if _, ok := open[sh.parentID]; ok {
+					remaining = append(remaining, sh)
+					continue
+				}
+
+				if err := s.readShard(ctx, sh, ch, filters...); err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					s.logf("Unable to read shard, %v: %v", sh.id, err)
+				}
+
+				if sh.iterator == nil {
+					delete(open, sh.id)
+					continue
+				}
+				remaining = append(remaining, sh)
+			}
+			sub.shards = remaining
+
+			timer := time.NewTimer(s.pollInterval)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+
+			s.refreshShards(ctx, sub)
+		}
+	}()
+
+	return ch
+}
+
+// refreshShards adds the shards of the table stream not yet seen to the subscription.  Shards open when the
+// subscription starts are read from their latest record; shards created afterwards are read from their beginning.
+func (s *Store) refreshShards(ctx context.Context, sub *subscription) {
+	if sub.streamArn == "" {
+		out, err := s.api.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(s.tableName),
+		})
+		if err != nil {
+			s.logf("Unable to describe table, %v: %v", s.tableName, err)
+			return
+		}
+		if out.Table.LatestStreamArn == nil {
+			s.logf("Streams not enabled for table, %v", s.tableName)
+			return
+		}
+		sub.streamArn = aws.StringValue(out.Table.LatestStreamArn)
+	}
+
+	iteratorType := dynamodbstreams.ShardIteratorTypeTrimHorizon
+	if !sub.started {
+		iteratorType = dynamodbstreams.ShardIteratorTypeLatest
+	}
+
+	shards, err := s.discoverShards(ctx, sub.streamArn, iteratorType, sub.seen)
+	sub.shards = append(sub.shards, shards...)
+	if err != nil {
+		s.logf("Unable to describe stream, %v: %v", sub.streamArn, err)
+		return
+	}
+	sub.started = true
+}
+
+// discoverShards returns the shards of the stream that have not yet been seen, positioned using iteratorType.  When
+// reading from the latest record, closed shards are skipped.  On error, the shards discovered so far are returned.
+func (s *Store) discoverShards(ctx context.Context, streamArn, iteratorType string, seen map[string]struct{}) ([]*shard, error) {
+	var shards []*shard
+
+	input := &dynamodbstreams.DescribeStreamInput{
+		StreamArn: aws.String(streamArn),
+	}
+	for {
+		out, err := s.streams.DescribeStreamWithContext(ctx, input)
+		if err != nil {
+			return shards, err
+		}
+
+		for _, item := range out.StreamDescription.Shards {
+			id := aws.StringValue(item.ShardId)
+			if _, ok := seen[id]; ok {
+				continue
+			}
+
+			closed := item.SequenceNumberRange != nil && item.SequenceNumberRange.EndingSequenceNumber != nil
+			if iteratorType == dynamodbstreams.ShardIteratorTypeLatest && closed {
+				seen[id] = struct{}{}
+				continue
+			}
+
+			iterator, err := s.streams.GetShardIteratorWithContext(ctx, &dynamodbstreams.GetShardIteratorInput{
+				StreamArn:         aws.String(streamArn),
+				ShardId:           item.ShardId,
+				ShardIteratorType: aws.String(iteratorType),
+			})
+			if err != nil {
+				return shards, err
+			}
+
+			seen[id] = struct{}{}
+			shards = append(shards, &shard{
+				id:       id,
+				parentID: aws.StringValue(item.ParentShardId),
+				iterator: iterator.ShardIterator,
+			})
+		}
+
+		if out.StreamDescription.LastEvaluatedShardId == nil {
+			break
+		}
+		input.ExclusiveStartShardId = out.StreamDescription.LastEvaluatedShardId
+	}
+
+	return shards, nil
+}
+
+// readShard reads the shard until no further records are available, delivering the records accepted by the filters to
+// ch.  The iterator of the shard is nil once a closed shard has been read to the end.
+func (s *Store) readShard(ctx context.Context, sh *shard, ch chan<- eventsource.StreamRecord, filters ...eventsource.Filter) error {
+	for sh.iterator != nil {
+		out, err := s.streams.GetRecordsWithContext(ctx, &dynamodbstreams.GetRecordsInput{
+			ShardIterator: sh.iterator,
+		})
+		if err != nil {
+			return err
+		}
+		sh.iterator = out.NextShardIterator
+
+		for _, item := range out.Records {
+			if item.Dynamodb == nil {
+				continue
+			}
+
+			records, err := s.StreamRecords(&dynamo.Record{
+				EventName: aws.StringValue(item.EventName),
+				Dynamodb: &dynamo.StreamRecord{
+					NewImage: item.Dynamodb.NewImage,
+					OldImage: item.Dynamodb.OldImage,
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, record := range records {
+				if !eventsource.Match(record, filters...) {
+					continue
+				}
+
+				select {
+				case ch <- record:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+
+		if len(out.Records) == 0 {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package dynamodbstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/savaki/eventsource"
+)
+
+const (
+	itemRevision = "revision"
+	itemVersion  = "version"
+)
+
+// conditionNotModified holds when the item has not been written to, or holds no version newer than :expected.  Items
+// written before the version was tracked, i.e. those with a revision but no version, fail the condition; see
+// upgradeLegacyItems.
+const conditionNotModified = "(attribute_not_exists(#revision) OR #version <= :expected)"
+
+// transactItems returns the updates of inputs as the items of a transaction.  Unless the item holding the version
+// following expectedVersion is already among them, a check is added that the item has not been written since
+// expectedVersion, so a save is rejected if any record newer than expectedVersion has been saved.
+func (s *Store) transactItems(aggregateID string, expectedVersion int, inputs []*dynamodb.UpdateItemInput) []*dynamodb.TransactWriteItem {
+	next := strconv.Itoa(selectPartition(expectedVersion+1, s.eventsPerItem))
+
+	checked := false
+	items := make([]*dynamodb.TransactWriteItem, 0, len(inputs)+1)
+	for _, input := range inputs {
+		if aws.StringValue(input.Key[s.rangeKey].N) == next {
+			checked = true
+		}
+
+		items = append(items, &dynamodb.TransactWriteItem{
+			Update: &dynamodb.Update{
+				TableName:                 input.TableName,
+				Key:                       input.Key,
+				ConditionExpression:       input.ConditionExpression,
+				UpdateExpression:          input.UpdateExpression,
+				ExpressionAttributeNames:  input.ExpressionAttributeNames,
+				ExpressionAttributeValues: input.ExpressionAttributeValues,
+			},
+		})
+	}
+
+	if !checked {
+		items = append(items, &dynamodb.TransactWriteItem{
+			ConditionCheck: &dynamodb.ConditionCheck{
+				TableName: aws.String(s.tableName),
+				Key: map[string]*dynamodb.AttributeValue{
+					s.hashKey:  {S: aws.String(aggregateID)},
+					s.rangeKey: {N: aws.String(next)},
+				},
+				ConditionExpression: aws.String(conditionNotModified),
+				ExpressionAttributeNames: map[string]*string{
+					"#revision": aws.String(itemRevision),
+					"#version":  aws.String(itemVersion),
+				},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":expected": {N: aws.String(strconv.Itoa(expectedVersion))},
+				},
+			},
+		})
+	}
+
+	return items
+}
+
+// saveItems writes the event items within a single transaction, so either every record is saved or none are.  Saves
+// that touch a single item are written using UpdateItem, which is atomic in itself and costs half as much.
+func (s *Store) saveItems(ctx context.Context, aggregateID string, expectedVersion int, items []*dynamodb.TransactWriteItem) error {
+	if n := len(items); n > maxTransactItems {
+		return fmt.Errorf("unable to save records for aggregate, %v; a transaction is limited to %v items and requires %v", aggregateID, maxTransactItems, n)
+	}
+
+	if s.debug {
+		encoder := json.NewEncoder(s.writer)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(items)
+	}
+
+	for attempt := 0; ; attempt++ {
+		var err error
+		if len(items) == 1 && items[0].Update != nil {
+			update := items[0].Update
+			_, err = s.api.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+				TableName:                 update.TableName,
+				Key:                       update.Key,
+				ConditionExpression:       update.ConditionExpression,
+				UpdateExpression:          update.UpdateExpression,
+				ExpressionAttributeNames:  update.ExpressionAttributeNames,
+				ExpressionAttributeValues: update.ExpressionAttributeValues,
+			})
+		} else {
+			_, err = s.api.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+				TransactItems: items,
+			})
+		}
+		if err == nil {
+			return nil
+		}
+
+		if !conditionFailed(err, len(items)) {
+			if v, ok := err.(awserr.Error); ok {
+				return fmt.Errorf("Save failed. %v [%v]: %w", v.Message(), v.Code(), err)
+			}
+			return err
+		}
+
+		if attempt == 0 {
+			upgraded, uerr := s.upgradeLegacyItems(ctx, items)
+			if uerr != nil {
+				return uerr
+			}
+			if upgraded {
+				continue
+			}
+		}
+
+		return eventsource.NewError(err, eventsource.DuplicateVersion, "aggregate, %v, modified since version %v", aggregateID, expectedVersion)
+	}
+}
+
+// conditionFailed returns true if err reports that the condition of any of the first n items of a transaction, or of
+// a single UpdateItem, failed
+func conditionFailed(err error, n int) bool {
+	if v, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		for index, reason := range v.CancellationReasons {
+			if index < n && aws.StringValue(reason.Code) == "ConditionalCheckFailed" {
+				return true
+			}
+		}
+		return false
+	}
+
+	v, ok := err.(awserr.Error)
+	return ok && v.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
+// upgradeLegacyItems sets the version of items saved before the most recent version of each item was tracked, i.e.
+// items with a revision but no version, to the most recent version they contain, so conditions on the version can be
+// evaluated against them.  Returns true if any item was upgraded, or was modified while being upgraded, in which case
+// the save should be attempted again.
+func (s *Store) upgradeLegacyItems(ctx context.Context, items []*dynamodb.TransactWriteItem) (bool, error) {
+	upgraded := false
+	for _, item := range items {
+		var key map[string]*dynamodb.AttributeValue
+		switch {
+		case item.Update != nil && aws.StringValue(item.Update.TableName) == s.tableName:
+			key = item.Update.Key
+		case item.ConditionCheck != nil && aws.StringValue(item.ConditionCheck.TableName) == s.tableName:
+			key = item.ConditionCheck.Key
+		default:
+			continue
+		}
+
+		out, err := s.api.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+			TableName:      aws.String(s.tableName),
+			Key:            key,
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return false, err
+		}
+
+		revision, ok := out.Item[itemRevision]
+		if _, tracked := out.Item[itemVersion]; !ok || tracked {
+			continue
+		}
+
+		version, found := 0, false
+		for name := range out.Item {
+			if !IsKey(name) {
+				continue
+			}
+			v, _, err := VersionAndAt(name)
+			if err != nil {
+				return false, err
+			}
+			if !found || v > version {
+				version, found = v, true
+			}
+		}
+		if !found {
+			continue
+		}
+
+		s.logf("Upgrading legacy item, %v, to version %v", aws.StringValue(key[s.rangeKey].N), version)
+		_, err = s.api.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName:           aws.String(s.tableName),
+			Key:                 key,
+			ConditionExpression: aws.String("attribute_not_exists(#version) AND #revision = :revision"),
+			UpdateExpression:    aws.String("SET #version = :version"),
+			ExpressionAttributeNames: map[string]*string{
+				"#revision": aws.String(itemRevision),
+				"#version":  aws.String(itemVersion),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":revision": revision,
+				":version":  {N: aws.String(strconv.Itoa(version))},
+			},
+		})
+		if err != nil {
+			if v, ok := err.(awserr.Error); !ok || v.Code() != dynamodb.ErrCodeConditionalCheckFailedException {
+				return false, err
+			}
+		}
+		upgraded = true
+	}
+
+	return upgraded, nil
+}
@@ -0,0 +1,63 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/savaki/eventsource"
+)
+
+const (
+	sqlReplaceCheckpoint = `REPLACE INTO {{ .TableName }} (name, position, at) VALUES (?, ?, ?)`
+	sqlSelectCheckpoint  = `SELECT position FROM {{ .TableName }} WHERE name = ?`
+)
+
+// CheckpointStore provides a sql backed projection.CheckpointStore
+type CheckpointStore struct {
+	openFunc   OpenFunc
+	tableName  string
+	replaceSQL string
+	selectSQL  string
+}
+
+// Load implements the projection.CheckpointStore interface
+func (s *CheckpointStore) Load(ctx context.Context, name string) (int64, error) {
+	db, err := s.openFunc()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var position int64
+	err = db.QueryRowContext(ctx, s.selectSQL, name).Scan(&position)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return position, nil
+}
+
+// Save implements the projection.CheckpointStore interface
+func (s *CheckpointStore) Save(ctx context.Context, name string, position int64) error {
+	db, err := s.openFunc()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, s.replaceSQL, name, position, eventsource.Now())
+	return err
+}
+
+// NewCheckpointStore returns a CheckpointStore backed by the table provided; see CreateCheckpointMySQL
+func NewCheckpointStore(tableName string, openFunc OpenFunc) *CheckpointStore {
+	return &CheckpointStore{
+		openFunc:   openFunc,
+		tableName:  tableName,
+		replaceSQL: reTableName.ReplaceAllString(sqlReplaceCheckpoint, tableName),
+		selectSQL:  reTableName.ReplaceAllString(sqlSelectCheckpoint, tableName),
+	}
+}
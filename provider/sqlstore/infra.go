@@ -14,16 +14,56 @@ const (
 	    offset    BIGINT(20) PRIMARY KEY NOT NULL AUTO_INCREMENT,
 	    id        VARCHAR(255),
 	    version   INT,
+	    type      VARCHAR(255),
 	    data      VARBINARY(8192),
-	    at        BIGINT(20)
+	    at        BIGINT(20),
+	    metadata  BLOB
 	) CHARACTER SET utf8 COLLATE utf8_unicode_ci AUTO_INCREMENT=10000;
 `
 
+	mysqlAddMetadata = `ALTER TABLE {{ .TableName }} ADD COLUMN metadata BLOB`
+	mysqlAddType     = `ALTER TABLE {{ .TableName }} ADD COLUMN type VARCHAR(255) AFTER version`
+
 	mysqlUniqueIndex = `CREATE UNIQUE INDEX idx_{{ .TableName }} ON {{ .TableName }} (id, version)`
+	mysqlTypeIndex   = `CREATE INDEX idx_{{ .TableName }}_type ON {{ .TableName }} (type, offset)`
+
+	mysqlCreateSnapshotTable = `
+	CREATE TABLE IF NOT EXISTS {{ .TableName }} (
+	    id        VARCHAR(255) NOT NULL,
+	    version   INT NOT NULL,
+	    data      MEDIUMBLOB,
+	    at        BIGINT(20),
+	    PRIMARY KEY (id, version)
+	) CHARACTER SET utf8 COLLATE utf8_unicode_ci;
+`
+
+	mysqlCreateOutboxTable = `
+	CREATE TABLE IF NOT EXISTS {{ .TableName }} (
+	    offset    BIGINT(20) PRIMARY KEY NOT NULL AUTO_INCREMENT,
+	    id        VARCHAR(255),
+	    version   INT,
+	    type      VARCHAR(255),
+	    data      VARBINARY(8192),
+	    at        BIGINT(20),
+	    metadata  BLOB,
+	    delivered BIGINT(20),
+	    INDEX idx_{{ .TableName }}_delivered (delivered, offset)
+	) CHARACTER SET utf8 COLLATE utf8_unicode_ci;
+`
+
+	mysqlCreateCheckpointTable = `
+	CREATE TABLE IF NOT EXISTS {{ .TableName }} (
+	    name      VARCHAR(255) NOT NULL,
+	    position  BIGINT(20) NOT NULL,
+	    at        BIGINT(20),
+	    PRIMARY KEY (name)
+	) CHARACTER SET utf8 COLLATE utf8_unicode_ci;
+`
 )
 
 var (
 	reTableName = regexp.MustCompile(`\{\{\s*.TableName\s*}}`)
+	reTypes     = regexp.MustCompile(`\{\{\s*.Types\s*}}`)
 )
 
 func CreateMySQL(ctx context.Context, db *sql.DB, tableName string) error {
@@ -34,8 +74,28 @@ func CreateMySQL(ctx context.Context, db *sql.DB, tableName string) error {
 		return err
 	}
 
+	// columns added after the initial release; applied to tables created by prior versions
+
+	columns := []string{
+		reTableName.ReplaceAllString(mysqlAddMetadata, tableName),
+		reTableName.ReplaceAllString(mysqlAddType, tableName),
+	}
+
+	for _, addColumnSQL := range columns {
+		_, err := db.ExecContext(ctx, addColumnSQL)
+		if err != nil {
+			if v, ok := err.(*mysql.MySQLError); ok {
+				if v.Number == 0x424 {
+					continue
+				}
+			}
+			return err
+		}
+	}
+
 	indexes := []string{
 		reTableName.ReplaceAllString(mysqlUniqueIndex, tableName),
+		reTableName.ReplaceAllString(mysqlTypeIndex, tableName),
 	}
 
 	for _, createIndexSQL := range indexes {
@@ -52,3 +112,27 @@ func CreateMySQL(ctx context.Context, db *sql.DB, tableName string) error {
 
 	return nil
 }
+
+// CreateSnapshotMySQL creates the table used by SnapshotStore
+func CreateSnapshotMySQL(ctx context.Context, db *sql.DB, tableName string) error {
+	createSQL := reTableName.ReplaceAllString(mysqlCreateSnapshotTable, tableName)
+
+	_, err := db.ExecContext(ctx, createSQL)
+	return err
+}
+
+// CreateCheckpointMySQL creates the table used by CheckpointStore
+func CreateCheckpointMySQL(ctx context.Context, db *sql.DB, tableName string) error {
+	createSQL := reTableName.ReplaceAllString(mysqlCreateCheckpointTable, tableName)
+
+	_, err := db.ExecContext(ctx, createSQL)
+	return err
+}
+
+// CreateOutboxMySQL creates the outbox table used by WithOutbox and Relay
+func CreateOutboxMySQL(ctx context.Context, db *sql.DB, tableName string) error {
+	createSQL := reTableName.ReplaceAllString(mysqlCreateOutboxTable, tableName)
+
+	_, err := db.ExecContext(ctx, createSQL)
+	return err
+}
@@ -1,6 +1,9 @@
 package sqlstore
 
-import "io"
+import (
+	"io"
+	"time"
+)
 
 type Option func(s *Store)
 
@@ -10,3 +13,18 @@ func WithDebug(w io.Writer) Option {
 		s.writer = w
 	}
 }
+
+// WithPollInterval specifies the interval at which Subscribe polls for new records; defaults to DefaultPollInterval
+func WithPollInterval(interval time.Duration) Option {
+	return func(s *Store) {
+		s.pollInterval = interval
+	}
+}
+
+// WithOutbox specifies a table, created using CreateOutboxMySQL, to which saved records are also written within the
+// same transaction; see Relay
+func WithOutbox(tableName string) Option {
+	return func(s *Store) {
+		s.outboxSQL = reTableName.ReplaceAllString(sqlInsertOutbox, tableName)
+	}
+}
@@ -0,0 +1,206 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/savaki/eventsource"
+)
+
+const (
+	sqlInsertOutbox  = `INSERT INTO {{ .TableName }} (id, version, type, data, at, metadata) VALUES (?, ?, ?, ?, ?, ?)`
+	sqlPendingOutbox = `SELECT offset, id, version, type, data, at, metadata FROM {{ .TableName }} WHERE delivered IS NULL ORDER BY offset LIMIT ?`
+	sqlMarkDelivered = `UPDATE {{ .TableName }} SET delivered = ? WHERE offset = ?`
+)
+
+const (
+	// DefaultRelayBatchSize is the default number of outbox records published per call to Publisher
+	DefaultRelayBatchSize = 100
+
+	// DefaultRelayPollInterval is the default interval at which the Relay checks the outbox once it has been drained
+	DefaultRelayPollInterval = time.Second
+)
+
+// Publisher publishes records from the outbox to another system e.g. a message broker
+type Publisher interface {
+	// Publish publishes the records, in offset order.  Records are marked delivered only once Publish returns nil;
+	// on error, or should marking them fail, the same records will be published again.  The Position of each
+	// record is its offset within the outbox and may be used by consumers to discard duplicates.
+	Publish(ctx context.Context, records ...eventsource.StreamRecord) error
+}
+
+// PublisherFunc provides a func implementation of Publisher
+type PublisherFunc func(ctx context.Context, records ...eventsource.StreamRecord) error
+
+// Publish implements the Publisher interface
+func (fn PublisherFunc) Publish(ctx context.Context, records ...eventsource.StreamRecord) error {
+	return fn(ctx, records...)
+}
+
+// Relay publishes the records written to an outbox table by a Store configured with WithOutbox.  Records are
+// delivered at least once; running more than one Relay against the same outbox increases the likelihood of
+// duplicates.
+type Relay struct {
+	openFunc     OpenFunc
+	publisher    Publisher
+	pendingSQL   string
+	deliveredSQL string
+	batchSize    int
+	pollInterval time.Duration
+}
+
+// RelayOption provides optional configuration to the Relay
+type RelayOption func(*Relay)
+
+// WithRelayBatchSize specifies the maximum number of records passed to each call to Publish; defaults to
+// DefaultRelayBatchSize.  Values less than 1 are ignored.
+func WithRelayBatchSize(batchSize int) RelayOption {
+	return func(r *Relay) {
+		if batchSize > 0 {
+			r.batchSize = batchSize
+		}
+	}
+}
+
+// WithRelayPollInterval specifies the interval at which Run checks the outbox once it has been drained; defaults to
+// DefaultRelayPollInterval
+func WithRelayPollInterval(pollInterval time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.pollInterval = pollInterval
+	}
+}
+
+// NewRelay returns a Relay that publishes the records of the outbox table; see CreateOutboxMySQL
+func NewRelay(tableName string, openFunc OpenFunc, publisher Publisher, opts ...RelayOption) *Relay {
+	r := &Relay{
+		openFunc:     openFunc,
+		publisher:    publisher,
+		pendingSQL:   reTableName.ReplaceAllString(sqlPendingOutbox, tableName),
+		deliveredSQL: reTableName.ReplaceAllString(sqlMarkDelivered, tableName),
+		batchSize:    DefaultRelayBatchSize,
+		pollInterval: DefaultRelayPollInterval,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Run publishes pending records until ctx is done, returning the error of ctx, or until publishing fails
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		if _, err := r.Flush(ctx); err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(r.pollInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Flush publishes pending records until none remain, returning the number of records published
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	db, err := r.openFunc()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	published := 0
+	for {
+		records, err := r.pending(ctx, db)
+		if err != nil {
+			return published, err
+		}
+		if len(records) == 0 {
+			return published, nil
+		}
+
+		if err := r.publisher.Publish(ctx, records...); err != nil {
+			return published, err
+		}
+
+		if err := r.markDelivered(ctx, db, records); err != nil {
+			return published, err
+		}
+		published += len(records)
+
+		if len(records) < r.batchSize {
+			return published, nil
+		}
+	}
+}
+
+// pending returns up to batchSize records not yet marked delivered, in offset order
+func (r *Relay) pending(ctx context.Context, db *sql.DB) ([]eventsource.StreamRecord, error) {
+	rows, err := db.QueryContext(ctx, r.pendingSQL, r.batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]eventsource.StreamRecord, 0, r.batchSize)
+	for rows.Next() {
+		record := eventsource.StreamRecord{}
+		eventType := sql.NullString{}
+		metadata := []byte{}
+		err := rows.Scan(&record.Position, &record.AggregateID, &record.Version, &eventType, &record.Data, &record.At, &metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		record.Type = eventType.String
+		if record.Metadata, err = unmarshalMetadata(metadata); err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// markDelivered marks each of the records delivered within a single transaction.  Records are marked individually, as
+// the offsets of pending records need not be contiguous.
+func (r *Relay) markDelivered(ctx context.Context, db *sql.DB, records []eventsource.StreamRecord) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = func() error {
+		stmt, err := tx.PrepareContext(ctx, r.deliveredSQL)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		now := eventsource.Now()
+		for _, record := range records {
+			if _, err := stmt.ExecContext(ctx, now, record.Position); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}()
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,200 @@
+package sqlstore_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/savaki/eventsource"
+	"github.com/savaki/eventsource/provider/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	sqliteCreateTable = `
+	CREATE TABLE entity_events (
+	    offset    INTEGER PRIMARY KEY AUTOINCREMENT,
+	    id        TEXT,
+	    version   INTEGER,
+	    type      TEXT,
+	    data      BLOB,
+	    at        INTEGER,
+	    metadata  BLOB,
+	    UNIQUE (id, version)
+	)
+`
+
+	sqliteCreateOutboxTable = `
+	CREATE TABLE entity_outbox (
+	    offset    INTEGER PRIMARY KEY AUTOINCREMENT,
+	    id        TEXT,
+	    version   INTEGER,
+	    type      TEXT,
+	    data      BLOB,
+	    at        INTEGER,
+	    metadata  BLOB,
+	    delivered INTEGER
+	)
+`
+)
+
+// openSQLite returns an OpenFunc for a new SQLite database containing the event and outbox tables
+func openSQLite(t *testing.T) sqlstore.OpenFunc {
+	filename := filepath.Join(t.TempDir(), "eventsource.db")
+	open := func() (*sql.DB, error) {
+		return sql.Open("sqlite3", filename)
+	}
+
+	db, err := open()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	for _, createSQL := range []string{sqliteCreateTable, sqliteCreateOutboxTable} {
+		_, err := db.Exec(createSQL)
+		assert.Nil(t, err)
+	}
+
+	return open
+}
+
+// recorder is a Publisher that records the published records and fails while err is set
+type recorder struct {
+	mux     sync.Mutex
+	err     error
+	calls   int
+	records []eventsource.StreamRecord
+}
+
+func (r *recorder) Publish(ctx context.Context, records ...eventsource.StreamRecord) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.calls++
+	if r.err != nil {
+		return r.err
+	}
+	r.records = append(r.records, records...)
+	return nil
+}
+
+func (r *recorder) published() []eventsource.StreamRecord {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return append([]eventsource.StreamRecord(nil), r.records...)
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	open := openSQLite(t)
+
+	store := sqlstore.New("entity_events", open, sqlstore.WithOutbox("entity_outbox"))
+	publisher := &recorder{}
+	relay := sqlstore.NewRelay("entity_outbox", open, publisher)
+
+	r1 := eventsource.Record{Version: 1, At: 1, Type: "Created", Data: []byte("a")}
+	r2 := eventsource.Record{Version: 2, At: 2, Type: "Renamed", Data: []byte("b"), Metadata: map[string]string{"actor": "joe"}}
+	err := store.Save(ctx, "abc", r1, r2)
+	assert.Nil(t, err)
+
+	// Test - Records are not marked delivered when Publish fails
+
+	publisher.err = errors.New("boom")
+	n, err := relay.Flush(ctx)
+	assert.Equal(t, publisher.err, err)
+	assert.Equal(t, 0, n)
+
+	// Test - Pending records are published, in order, and marked delivered
+
+	publisher.err = nil
+	n, err = relay.Flush(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	published := publisher.published()
+	assert.Len(t, published, 2)
+	assert.Equal(t, r1, published[0].Record)
+	assert.Equal(t, r2, published[1].Record)
+	assert.Equal(t, "abc", published[1].AggregateID)
+	assert.True(t, published[0].Position < published[1].Position)
+
+	n, err = relay.Flush(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	// Test - Records are written to the outbox only if the events are saved
+
+	err = store.Save(ctx, "abc", eventsource.Record{Version: 3, Data: []byte("c")}, r2)
+	assert.NotNil(t, err)
+
+	n, err = relay.Flush(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestRelay_BatchSize(t *testing.T) {
+	ctx := context.Background()
+	open := openSQLite(t)
+
+	store := sqlstore.New("entity_events", open, sqlstore.WithOutbox("entity_outbox"))
+	publisher := &recorder{}
+	relay := sqlstore.NewRelay("entity_outbox", open, publisher, sqlstore.WithRelayBatchSize(2))
+
+	for version := 1; version <= 5; version++ {
+		err := store.Save(ctx, "abc", eventsource.Record{Version: version, Data: []byte("a")})
+		assert.Nil(t, err)
+	}
+
+	n, err := relay.Flush(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, 3, publisher.calls)
+
+	for index, record := range publisher.published() {
+		assert.Equal(t, index+1, record.Version)
+	}
+
+	// Test - Non-positive batch sizes are ignored rather than publishing nothing
+
+	for _, batchSize := range []int{0, -1} {
+		err := store.Save(ctx, "def", eventsource.Record{Version: -batchSize + 1, Data: []byte("b")})
+		assert.Nil(t, err)
+
+		n, err := sqlstore.NewRelay("entity_outbox", open, publisher, sqlstore.WithRelayBatchSize(batchSize)).Flush(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, n)
+	}
+}
+
+func TestRelay_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	open := openSQLite(t)
+
+	store := sqlstore.New("entity_events", open, sqlstore.WithOutbox("entity_outbox"))
+	publisher := &recorder{}
+	relay := sqlstore.NewRelay("entity_outbox", open, publisher, sqlstore.WithRelayPollInterval(10*time.Millisecond))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- relay.Run(ctx)
+	}()
+
+	err := store.Save(ctx, "abc", eventsource.Record{Version: 1, Data: []byte("a")})
+	assert.Nil(t, err)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(publisher.published()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Len(t, publisher.published(), 1)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
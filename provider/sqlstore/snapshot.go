@@ -0,0 +1,68 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"math"
+
+	"github.com/savaki/eventsource"
+)
+
+const (
+	sqlReplaceSnapshot = `REPLACE INTO {{ .TableName }} (id, version, data, at) VALUES (?, ?, ?, ?)`
+	sqlSelectSnapshot  = `SELECT version, data, at FROM {{ .TableName }} WHERE id = ? and version <= ? ORDER BY version DESC LIMIT 1`
+)
+
+// SnapshotStore provides a sql backed eventsource.SnapshotStore
+type SnapshotStore struct {
+	openFunc   OpenFunc
+	tableName  string
+	replaceSQL string
+	selectSQL  string
+}
+
+// Save implements the eventsource.SnapshotStore interface
+func (s *SnapshotStore) Save(ctx context.Context, snapshot eventsource.Snapshot) error {
+	db, err := s.openFunc()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, s.replaceSQL, snapshot.AggregateID, snapshot.Version, snapshot.Data, snapshot.At)
+	return err
+}
+
+// Fetch implements the eventsource.SnapshotStore interface
+func (s *SnapshotStore) Fetch(ctx context.Context, aggregateID string, version int) (eventsource.Snapshot, error) {
+	if version == 0 {
+		version = math.MaxInt32
+	}
+
+	db, err := s.openFunc()
+	if err != nil {
+		return eventsource.Snapshot{}, err
+	}
+	defer db.Close()
+
+	snapshot := eventsource.Snapshot{AggregateID: aggregateID}
+	err = db.QueryRowContext(ctx, s.selectSQL, aggregateID, version).Scan(&snapshot.Version, &snapshot.Data, &snapshot.At)
+	if err == sql.ErrNoRows {
+		return eventsource.Snapshot{}, eventsource.NewError(nil, eventsource.SnapshotNotFound, "no snapshot found for aggregate id, %v", aggregateID)
+	}
+	if err != nil {
+		return eventsource.Snapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+// NewSnapshotStore returns a SnapshotStore backed by the table provided; see CreateSnapshotMySQL
+func NewSnapshotStore(tableName string, openFunc OpenFunc) *SnapshotStore {
+	return &SnapshotStore{
+		openFunc:   openFunc,
+		tableName:  tableName,
+		replaceSQL: reTableName.ReplaceAllString(sqlReplaceSnapshot, tableName),
+		selectSQL:  reTableName.ReplaceAllString(sqlSelectSnapshot, tableName),
+	}
+}
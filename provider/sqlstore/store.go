@@ -3,20 +3,41 @@ package sqlstore
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"math"
 	"sort"
+	"strings"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/savaki/eventsource"
 )
 
 const (
-	sqlInsert        = `INSERT INTO {{ .TableName }} (id, version, data, at) VALUES (?, ?, ?, ?)`
-	sqlSelectVersion = `SELECT version, data, at FROM {{ .TableName }} WHERE id = ? and version <= ?`
-	sqlSelect        = `SELECT version, data, at FROM {{ .TableName }} WHERE id = ?`
+	sqlInsert        = `INSERT INTO {{ .TableName }} (id, version, type, data, at, metadata) VALUES (?, ?, ?, ?, ?, ?)`
+	sqlSelectVersion = `SELECT version, type, data, at, metadata FROM {{ .TableName }} WHERE id = ? and version <= ?`
+	sqlSelect        = `SELECT version, type, data, at, metadata FROM {{ .TableName }} WHERE id = ?`
+	sqlSelectAfter   = `SELECT version, type, data, at, metadata FROM {{ .TableName }} WHERE id = ? and version > ?`
+	sqlCountNewer    = `SELECT COUNT(*) FROM {{ .TableName }} WHERE id = ? and version > ?`
+	sqlReadTypes     = `SELECT offset, id, version, type, data, at, metadata FROM {{ .TableName }} WHERE offset > ? AND type IN ({{ .Types }}) ORDER BY offset LIMIT ?`
+	sqlRead          = `SELECT offset, id, version, type, data, at, metadata FROM {{ .TableName }} WHERE offset > ? ORDER BY offset LIMIT ?`
+	sqlMaxOffset     = `SELECT COALESCE(MAX(offset), 0) FROM {{ .TableName }}`
+)
+
+const (
+	// DefaultPollInterval is the default interval at which Subscribe polls for new records
+	DefaultPollInterval = time.Second
+
+	// subscribeBatchSize is the number of records Subscribe reads per query
+	subscribeBatchSize = 100
+)
+
+const (
+	// mysqlDuplicateEntry is the mysql error number returned on a unique index violation
+	mysqlDuplicateEntry = 1062
 )
 
 type OpenFunc func() (*sql.DB, error)
@@ -27,18 +48,50 @@ type Store struct {
 	insertSQL        string
 	selectSQL        string
 	selectVersionSQL string
+	selectAfterSQL   string
+	countNewerSQL    string
+	readSQL          string
+	readTypesSQL     string
+	maxOffsetSQL     string
+	outboxSQL        string
+	pollInterval     time.Duration
 	debug            bool
 	writer           io.Writer
 }
 
 func (s *Store) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
+	return s.save(ctx, aggregateID, nil, records...)
+}
+
+// SaveVersion implements the eventsource.VersionSaver interface
+func (s *Store) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...eventsource.Record) error {
+	return s.save(ctx, aggregateID, func(tx *sql.Tx) error {
+		s.log("Verifying aggregate is at version,", expectedVersion)
+
+		count := 0
+		err := tx.QueryRowContext(ctx, s.countNewerSQL, aggregateID, expectedVersion).Scan(&count)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return eventsource.NewError(nil, eventsource.DuplicateVersion, "aggregate, %v, modified since version %v", aggregateID, expectedVersion)
+		}
+
+		return nil
+	}, records...)
+}
+
+// save inserts the records, along with their outbox entries, within a single transaction; before, if provided, is
+// executed within the transaction prior to the records being inserted
+func (s *Store) save(ctx context.Context, aggregateID string, before func(tx *sql.Tx) error, records ...eventsource.Record) error {
 	db, err := s.openFunc()
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -46,30 +99,61 @@ func (s *Store) Save(ctx context.Context, aggregateID string, records ...eventso
 	s.log("Saving", len(records), "events.")
 
 	err = func(tx *sql.Tx) error {
-		stmt, err := tx.Prepare(s.insertSQL)
+		if before != nil {
+			if err := before(tx); err != nil {
+				return err
+			}
+		}
+
+		stmt, err := tx.PrepareContext(ctx, s.insertSQL)
 		if err != nil {
 			return err
 		}
 		defer stmt.Close()
 
+		var outbox *sql.Stmt
+		if s.outboxSQL != "" {
+			outbox, err = tx.PrepareContext(ctx, s.outboxSQL)
+			if err != nil {
+				return err
+			}
+			defer outbox.Close()
+		}
+
 		for _, record := range records {
 			s.log("Saving version,", record.Version)
-			_, err = stmt.Exec(aggregateID, record.Version, record.Data, record.At)
+			metadata, err := marshalMetadata(record.Metadata)
+			if err != nil {
+				return err
+			}
+
+			_, err = stmt.ExecContext(ctx, aggregateID, record.Version, nullString(record.Type), record.Data, record.At, metadata)
 			if err != nil {
+				if v, ok := err.(*mysql.MySQLError); ok && v.Number == mysqlDuplicateEntry {
+					return eventsource.NewError(err, eventsource.DuplicateVersion, "aggregate, %v, already contains version %v", aggregateID, record.Version)
+				}
 				return err
 			}
+
+			if outbox != nil {
+				_, err = outbox.ExecContext(ctx, aggregateID, record.Version, nullString(record.Type), record.Data, record.At, metadata)
+				if err != nil {
+					return err
+				}
+			}
 		}
 
 		return nil
 	}(tx)
 
-	if err == nil {
-		s.log("Ok")
-		return tx.Commit()
-	} else {
+	if err != nil {
 		s.log("Failed.  Rolling back transaction.")
-		return tx.Rollback()
+		tx.Rollback()
+		return err
 	}
+
+	s.log("Ok")
+	return tx.Commit()
 }
 
 func (s *Store) Fetch(ctx context.Context, aggregateID string, version int) (eventsource.History, error) {
@@ -77,41 +161,74 @@ func (s *Store) Fetch(ctx context.Context, aggregateID string, version int) (eve
 		version = math.MaxInt32
 	}
 
+	query := s.selectSQL
+	if version > 0 {
+		query = s.selectVersionSQL
+	}
+
+	s.log("Reading events with aggregrateID,", aggregateID)
+	history, err := s.query(ctx, query, aggregateID, version)
+	if err != nil {
+		return eventsource.History{}, err
+	}
+
+	if len(history) == 0 {
+		return eventsource.History{}, eventsource.NewError(nil, eventsource.AggregateNotFound, "no aggregate found with id, %v", aggregateID)
+	}
+
+	return history, nil
+}
+
+// FetchAfter implements the eventsource.AfterFetcher interface
+func (s *Store) FetchAfter(ctx context.Context, aggregateID string, version int) (eventsource.History, error) {
+	s.log("Reading events with aggregrateID,", aggregateID, "after version,", version)
+	return s.query(ctx, s.selectAfterSQL, aggregateID, version)
+}
+
+// query executes a query returning version, data, and at columns and returns the rows as History sorted by version
+func (s *Store) query(ctx context.Context, query string, args ...interface{}) (eventsource.History, error) {
 	db, err := s.openFunc()
 	if err != nil {
 		return eventsource.History{}, err
 	}
 	defer db.Close()
 
-	s.log("Reading events with aggregrateID,", aggregateID)
-	query := s.selectSQL
-	if version > 0 {
-		query = s.selectVersionSQL
-	}
-	rows, err := db.QueryContext(ctx, query, aggregateID, version)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return eventsource.History{}, err
 	}
 	defer rows.Close()
 
 	s.log("Scanning rows")
-	history := make(eventsource.History, 0, version+1)
+	history := eventsource.History{}
 	for rows.Next() {
 		s.log("Scanning row")
 		version := 0
+		eventType := sql.NullString{}
 		data := []byte{}
 		at := eventsource.EpochMillis(0)
-		err := rows.Scan(&version, &data, &at)
+		metadata := []byte{}
+		err := rows.Scan(&version, &eventType, &data, &at, &metadata)
 		if err != nil {
 			return eventsource.History{}, err
 		}
 
-		s.log("Reading version,", version)
-		history = append(history, eventsource.Record{
+		record := eventsource.Record{
 			Version: version,
 			At:      at,
+			Type:    eventType.String,
 			Data:    data,
-		})
+		}
+		if record.Metadata, err = unmarshalMetadata(metadata); err != nil {
+			return eventsource.History{}, err
+		}
+
+		s.log("Reading version,", version)
+		history = append(history, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return eventsource.History{}, err
 	}
 
 	sort.Slice(history, func(i, j int) bool {
@@ -122,6 +239,183 @@ func (s *Store) Fetch(ctx context.Context, aggregateID string, version int) (eve
 	return history, nil
 }
 
+// Read implements the eventsource.Reader interface using the offset column as the position.  Offsets are assigned on
+// insert, so records from transactions that commit out of order may appear behind the position of a prior Read; see
+// projection.WithGapTimeout.
+func (s *Store) Read(ctx context.Context, startingPosition int64, recordCount int) ([]eventsource.StreamRecord, error) {
+	s.log("Reading", recordCount, "events after offset,", startingPosition)
+	return s.read(ctx, recordCount, s.readSQL, startingPosition, recordCount)
+}
+
+// ReadTypes implements the eventsource.TypeReader interface; see Read
+func (s *Store) ReadTypes(ctx context.Context, startingPosition int64, recordCount int, eventTypes ...string) ([]eventsource.StreamRecord, error) {
+	if len(eventTypes) == 0 {
+		return []eventsource.StreamRecord{}, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(eventTypes)), ", ")
+	query := reTypes.ReplaceAllString(s.readTypesSQL, placeholders)
+
+	args := make([]interface{}, 0, len(eventTypes)+2)
+	args = append(args, startingPosition)
+	for _, eventType := range eventTypes {
+		args = append(args, eventType)
+	}
+	args = append(args, recordCount)
+
+	s.log("Reading", recordCount, "events of type,", eventTypes, "after offset,", startingPosition)
+	return s.read(ctx, recordCount, query, args...)
+}
+
+// read executes a query against the log and returns the rows as StreamRecords
+func (s *Store) read(ctx context.Context, recordCount int, query string, args ...interface{}) ([]eventsource.StreamRecord, error) {
+	if recordCount <= 0 {
+		return []eventsource.StreamRecord{}, nil
+	}
+
+	db, err := s.openFunc()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]eventsource.StreamRecord, 0, recordCount)
+	for rows.Next() {
+		record := eventsource.StreamRecord{}
+		eventType := sql.NullString{}
+		metadata := []byte{}
+		err := rows.Scan(&record.Position, &record.AggregateID, &record.Version, &eventType, &record.Data, &record.At, &metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		record.Type = eventType.String
+		if record.Metadata, err = unmarshalMetadata(metadata); err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	s.log("Successfully read", len(records), "events")
+	return records, nil
+}
+
+// Subscribe implements the eventsource.Subscriber interface by polling the log for records with an offset greater
+// than the last offset seen.  Errors are logged and the query retried after the poll interval.  As with Read, records
+// from transactions that commit out of order may be missed.
+func (s *Store) Subscribe(ctx context.Context, filters ...eventsource.Filter) <-chan eventsource.StreamRecord {
+	ch := make(chan eventsource.StreamRecord)
+	position, err := s.maxOffset(ctx)
+
+	go func() {
+		defer close(ch)
+
+		for err != nil {
+			s.log("Unable to read max offset,", err)
+			if !s.wait(ctx) {
+				return
+			}
+			position, err = s.maxOffset(ctx)
+		}
+
+		for {
+			records, err := s.Read(ctx, position, subscribeBatchSize)
+			if err != nil {
+				s.log("Unable to read events after offset,", position, err)
+			}
+
+			for _, record := range records {
+				position = record.Position
+				if !eventsource.Match(record, filters...) {
+					continue
+				}
+
+				select {
+				case ch <- record:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if len(records) == subscribeBatchSize {
+				continue
+			}
+
+			if !s.wait(ctx) {
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// maxOffset returns the offset of the most recently inserted record; 0 if the log is empty
+func (s *Store) maxOffset(ctx context.Context) (int64, error) {
+	db, err := s.openFunc()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var offset int64
+	if err := db.QueryRowContext(ctx, s.maxOffsetSQL).Scan(&offset); err != nil {
+		return 0, err
+	}
+
+	return offset, nil
+}
+
+// wait blocks for the poll interval; returns false if ctx is done first
+func (s *Store) wait(ctx context.Context) bool {
+	timer := time.NewTimer(s.pollInterval)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// nullString stores empty strings as NULL
+func nullString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
+
+// marshalMetadata encodes the record metadata as json; nil metadata is stored as NULL
+func marshalMetadata(metadata map[string]string) ([]byte, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(metadata)
+}
+
+// unmarshalMetadata decodes the record metadata stored by marshalMetadata
+func unmarshalMetadata(data []byte) (map[string]string, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	metadata := map[string]string{}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to unmarshal metadata")
+	}
+	return metadata, nil
+}
+
 func (s *Store) log(args ...interface{}) {
 	if !s.debug {
 		return
@@ -135,6 +429,11 @@ func New(tableName string, openFunc OpenFunc, opts ...Option) *Store {
 	insertSQL := reTableName.ReplaceAllString(sqlInsert, tableName)
 	selectSQL := reTableName.ReplaceAllString(sqlSelect, tableName)
 	selectVersionSQL := reTableName.ReplaceAllString(sqlSelectVersion, tableName)
+	selectAfterSQL := reTableName.ReplaceAllString(sqlSelectAfter, tableName)
+	countNewerSQL := reTableName.ReplaceAllString(sqlCountNewer, tableName)
+	readSQL := reTableName.ReplaceAllString(sqlRead, tableName)
+	readTypesSQL := reTableName.ReplaceAllString(sqlReadTypes, tableName)
+	maxOffsetSQL := reTableName.ReplaceAllString(sqlMaxOffset, tableName)
 
 	s := &Store{
 		openFunc:         openFunc,
@@ -142,6 +441,12 @@ func New(tableName string, openFunc OpenFunc, opts ...Option) *Store {
 		insertSQL:        insertSQL,
 		selectSQL:        selectSQL,
 		selectVersionSQL: selectVersionSQL,
+		selectAfterSQL:   selectAfterSQL,
+		countNewerSQL:    countNewerSQL,
+		readSQL:          readSQL,
+		readTypesSQL:     readTypesSQL,
+		maxOffsetSQL:     maxOffsetSQL,
+		pollInterval:     DefaultPollInterval,
 		writer:           ioutil.Discard,
 	}
 
@@ -9,6 +9,7 @@ import (
 
 	"github.com/savaki/eventsource"
 	"github.com/savaki/eventsource/provider/sqlstore"
+	"github.com/savaki/eventsource/storetest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -70,3 +71,231 @@ func TestStore_Save(t *testing.T) {
 	assert.Equal(t, eventsource.History{r1, r2}, history)
 	assert.Equal(t, e2.Model.Version, history[1].Version)
 }
+
+func TestStore_SaveVersion(t *testing.T) {
+	ctx := context.Background()
+	tableName := "entity_events"
+
+	db := MustOpen()
+	err := sqlstore.CreateMySQL(ctx, db, tableName)
+	assert.Nil(t, err)
+	db.Close()
+
+	aggregateID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	r1 := eventsource.Record{Version: 1, Data: []byte("a")}
+	r2 := eventsource.Record{Version: 2, Data: []byte("b")}
+
+	store := sqlstore.New(tableName, Open)
+
+	err = store.SaveVersion(ctx, aggregateID, 0, r1)
+	assert.Nil(t, err)
+
+	// Test - Saving against a stale version should fail with DuplicateVersion
+
+	err = store.SaveVersion(ctx, aggregateID, 0, r2)
+	assert.NotNil(t, err)
+	v, ok := err.(eventsource.Error)
+	assert.True(t, ok)
+	assert.Equal(t, eventsource.DuplicateVersion, v.Code())
+
+	// Test - Saving a duplicate version should fail with DuplicateVersion
+
+	err = store.Save(ctx, aggregateID, r1)
+	assert.NotNil(t, err)
+	v, ok = err.(eventsource.Error)
+	assert.True(t, ok)
+	assert.Equal(t, eventsource.DuplicateVersion, v.Code())
+
+	history, err := store.Fetch(ctx, aggregateID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, eventsource.History{r1}, history)
+}
+
+func TestStore_Read(t *testing.T) {
+	ctx := context.Background()
+	tableName := "entity_events"
+
+	db := MustOpen()
+	err := sqlstore.CreateMySQL(ctx, db, tableName)
+	assert.Nil(t, err)
+	db.Close()
+
+	store := sqlstore.New(tableName, Open)
+
+	aggregateID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	r1 := eventsource.Record{Version: 1, Data: []byte("a")}
+	r2 := eventsource.Record{Version: 2, Data: []byte("b")}
+	err = store.Save(ctx, aggregateID, r1, r2)
+	assert.Nil(t, err)
+
+	// Test - Read the log until our records are found
+
+	var found []eventsource.StreamRecord
+	var position int64
+	for {
+		records, err := store.Read(ctx, position, 100)
+		assert.Nil(t, err)
+		if len(records) == 0 {
+			break
+		}
+
+		for _, record := range records {
+			assert.True(t, record.Position > position)
+			position = record.Position
+			if record.AggregateID == aggregateID {
+				found = append(found, record)
+			}
+		}
+	}
+
+	assert.Len(t, found, 2)
+	assert.Equal(t, r1, found[0].Record)
+	assert.Equal(t, r2, found[1].Record)
+}
+
+func TestSnapshotStore(t *testing.T) {
+	ctx := context.Background()
+	tableName := "entity_snapshots"
+
+	db := MustOpen()
+	err := sqlstore.CreateSnapshotMySQL(ctx, db, tableName)
+	assert.Nil(t, err)
+	db.Close()
+
+	store := sqlstore.NewSnapshotStore(tableName, Open)
+
+	aggregateID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	_, err = store.Fetch(ctx, aggregateID, 0)
+	assert.NotNil(t, err)
+
+	s1 := eventsource.Snapshot{AggregateID: aggregateID, Version: 1, At: 1, Data: []byte("a")}
+	s2 := eventsource.Snapshot{AggregateID: aggregateID, Version: 3, At: 2, Data: []byte("b")}
+	assert.Nil(t, store.Save(ctx, s1))
+	assert.Nil(t, store.Save(ctx, s2))
+
+	found, err := store.Fetch(ctx, aggregateID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, s2, found)
+
+	found, err = store.Fetch(ctx, aggregateID, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, s1, found)
+}
+
+func TestCheckpointStore(t *testing.T) {
+	ctx := context.Background()
+	tableName := "entity_checkpoints"
+
+	db := MustOpen()
+	err := sqlstore.CreateCheckpointMySQL(ctx, db, tableName)
+	assert.Nil(t, err)
+	db.Close()
+
+	store := sqlstore.NewCheckpointStore(tableName, Open)
+
+	name := strconv.FormatInt(time.Now().UnixNano(), 10)
+	position, err := store.Load(ctx, name)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), position)
+
+	assert.Nil(t, store.Save(ctx, name, 123))
+
+	position, err = store.Load(ctx, name)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(123), position)
+}
+
+func TestStore_Metadata(t *testing.T) {
+	ctx := context.Background()
+	tableName := "entity_events"
+
+	db := MustOpen()
+	err := sqlstore.CreateMySQL(ctx, db, tableName)
+	assert.Nil(t, err)
+	db.Close()
+
+	aggregateID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	r1 := eventsource.Record{Version: 1, Data: []byte("a"), Metadata: map[string]string{eventsource.MetadataActor: "joe"}}
+	r2 := eventsource.Record{Version: 2, Data: []byte("b")}
+
+	store := sqlstore.New(tableName, Open)
+	err = store.Save(ctx, aggregateID, r1, r2)
+	assert.Nil(t, err)
+
+	history, err := store.Fetch(ctx, aggregateID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, eventsource.History{r1, r2}, history)
+}
+
+func TestStore_ReadTypes(t *testing.T) {
+	ctx := context.Background()
+	tableName := "entity_events"
+
+	db := MustOpen()
+	err := sqlstore.CreateMySQL(ctx, db, tableName)
+	assert.Nil(t, err)
+	db.Close()
+
+	store := sqlstore.New(tableName, Open)
+
+	aggregateID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	eventType := "Type" + aggregateID
+	r1 := eventsource.Record{Version: 1, Type: eventType, Data: []byte("a")}
+	r2 := eventsource.Record{Version: 2, Type: "Other", Data: []byte("b")}
+	err = store.Save(ctx, aggregateID, r1, r2)
+	assert.Nil(t, err)
+
+	records, err := store.ReadTypes(ctx, 0, 100, eventType)
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, r1, records[0].Record)
+	assert.Equal(t, aggregateID, records[0].AggregateID)
+}
+
+func TestStore_Conformance(t *testing.T) {
+	ctx := context.Background()
+	tableName := "entity_events"
+
+	db := MustOpen()
+	err := sqlstore.CreateMySQL(ctx, db, tableName)
+	assert.Nil(t, err)
+	db.Close()
+
+	storetest.TestStore(t, func(t *testing.T) eventsource.Store {
+		return sqlstore.New(tableName, Open)
+	})
+}
+
+func TestStore_Subscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tableName := "entity_events"
+
+	db := MustOpen()
+	err := sqlstore.CreateMySQL(ctx, db, tableName)
+	assert.Nil(t, err)
+	db.Close()
+
+	store := sqlstore.New(tableName, Open, sqlstore.WithPollInterval(50*time.Millisecond))
+
+	aggregateID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	r1 := eventsource.Record{Version: 1, Type: "Created", Data: []byte("a")}
+	r2 := eventsource.Record{Version: 2, Type: "Renamed", Data: []byte("b")}
+
+	ch := store.Subscribe(ctx, eventsource.FilterAggregateID(aggregateID), eventsource.FilterEventType("Renamed"))
+	err = store.Save(ctx, aggregateID, r1, r2)
+	assert.Nil(t, err)
+
+	select {
+	case record := <-ch:
+		assert.Equal(t, r2, record.Record)
+		assert.Equal(t, aggregateID, record.AggregateID)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for record")
+	}
+
+	cancel()
+	for range ch {
+	}
+}
@@ -25,7 +25,25 @@ type Aggregate interface {
 type Repository interface {
 	Bind(events ...Event) error
 	Load(ctx context.Context, aggregateID string) (Aggregate, error)
+
+	// LoadWithVersion loads the most recent version of the aggregate along with the version of the last event applied
+	// to it; pass the version to SaveVersion when saving the events derived from the aggregate
+	LoadWithVersion(ctx context.Context, aggregateID string) (Aggregate, int, error)
+
+	// LoadVersion loads the aggregate as it was once the event with the specified version had been applied; 0 to load
+	// the most recent version
+	LoadVersion(ctx context.Context, aggregateID string, version int) (Aggregate, error)
+
+	// LoadAt loads the aggregate as it was at the time provided, applying events up to the first whose Record.At is
+	// after t
+	LoadAt(ctx context.Context, aggregateID string, t time.Time) (Aggregate, error)
+
 	Save(ctx context.Context, events ...Event) error
+
+	// SaveVersion saves the events provided the aggregate is still at expectedVersion, typically the version returned
+	// by LoadWithVersion; otherwise an Error with the DuplicateVersion code is returned
+	SaveVersion(ctx context.Context, expectedVersion int, events ...Event) error
+
 	New() Aggregate
 }
 
@@ -33,9 +51,15 @@ type repository struct {
 	prototype  reflect.Type
 	store      Store
 	serializer Serializer
-	types      map[string]reflect.Type
+	types      *TypeRegistry
 	writer     io.Writer
 	debug      bool
+
+	snapshots     SnapshotStore
+	snapshotEvery int
+
+	cache   *Cache
+	routing bool
 }
 
 func New(prototype Aggregate, opts ...Option) Repository {
@@ -45,16 +69,27 @@ func New(prototype Aggregate, opts ...Option) Repository {
 	}
 
 	r := &repository{
-		prototype:  t,
-		store:      newMemoryStore(),
-		serializer: JSONSerializer(),
-		types:      map[string]reflect.Type{},
+		prototype: t,
+		store:     NewMemoryStore(),
+		types:     NewTypeRegistry(),
 	}
 
 	for _, opt := range opts {
 		opt(r)
 	}
 
+	if r.serializer == nil {
+		r.serializer = JSONSerializer(WithJSONTypeRegistry(r.types))
+	}
+
+	if _, ok := r.New().(Snapshotter); r.snapshots != nil && !ok {
+		panic(fmt.Sprintf("eventsource: WithSnapshots requires the aggregate, %v, to implement Snapshotter", r.prototype))
+	}
+
+	if _, ok := r.New().(Snapshotter); r.cache != nil && !ok {
+		panic(fmt.Sprintf("eventsource: WithCache requires the aggregate, %v, to implement Snapshotter", r.prototype))
+	}
+
 	return r
 }
 
@@ -92,17 +127,27 @@ func EventType(event Event) (string, reflect.Type) {
 func (r *repository) Bind(events ...Event) error {
 	for _, event := range events {
 		if event == nil {
-			return errors.New("attempt to bind nil event")
+			return NewError(nil, UnboundEventType, "attempt to bind nil event")
+		}
+
+		if r.routing {
+			if err := ValidateRoutes(r.New(), event); err != nil {
+				return err
+			}
+		}
+
+		err := r.types.Register(event)
+		if err != nil {
+			return err
 		}
 
-		err := r.serializer.Bind(event)
+		err = r.serializer.Bind(event)
 		if err != nil {
 			return err
 		}
 
-		eventType, typ := EventType(event)
+		eventType, _ := EventType(event)
 		r.logf("Binding %12s => %#v", eventType, event)
-		r.types[eventType] = typ
 	}
 
 	return nil
@@ -113,55 +158,316 @@ func (r *repository) New() Aggregate {
 	return reflect.New(r.prototype).Interface().(Aggregate)
 }
 
+// Save serializes and saves the events to the Store along with any metadata attached to the context via
+// ContextWithMetadata.  Save does not check the version of the aggregate, so events saved concurrently are only
+// rejected if their versions collide; use SaveVersion for optimistic concurrency control.
 func (r *repository) Save(ctx context.Context, events ...Event) error {
-	if len(events) == 0 {
-		return nil
+	aggregateID, history, err := r.serialize(ctx, events)
+	if err != nil || len(history) == 0 {
+		return err
 	}
 
+	return r.invalidateOnConflict(aggregateID, r.store.Save(ctx, aggregateID, history...))
+}
+
+// SaveVersion implements the Repository interface.  When the Store implements VersionSaver, the save fails with
+// DuplicateVersion if any event has been saved for the aggregate since expectedVersion; otherwise SaveVersion behaves
+// as Save.
+func (r *repository) SaveVersion(ctx context.Context, expectedVersion int, events ...Event) error {
+	aggregateID, history, err := r.serialize(ctx, events)
+	if err != nil || len(history) == 0 {
+		return err
+	}
+
+	if v, ok := r.store.(VersionSaver); ok {
+		err = v.SaveVersion(ctx, aggregateID, expectedVersion, history...)
+	} else {
+		err = r.store.Save(ctx, aggregateID, history...)
+	}
+
+	return r.invalidateOnConflict(aggregateID, err)
+}
+
+// serialize serializes the events, merging in the metadata attached to the context, and returns them along with the
+// id of their aggregate
+func (r *repository) serialize(ctx context.Context, events []Event) (string, History, error) {
 	var aggregateID string
+	metadata := MetadataFromContext(ctx)
 	history := make(History, 0, len(events))
 	for _, event := range events {
 		record, err := r.serializer.Serialize(event)
 		if err != nil {
-			return err
+			return "", nil, err
 		}
+		record.Metadata = mergeMetadata(metadata, record.Metadata)
 
 		aggregateID = event.AggregateID()
 
 		history = append(history, record)
 	}
 
-	return r.store.Save(ctx, aggregateID, history...)
+	return aggregateID, history, nil
+}
+
+// invalidateOnConflict removes the aggregate from the cache if err indicates the cached version is stale
+func (r *repository) invalidateOnConflict(aggregateID string, err error) error {
+	if r.cache != nil && errors.Is(err, ErrDuplicateVersion) {
+		r.cache.Invalidate(aggregateID)
+	}
+	return err
 }
 
 func (r *repository) Load(ctx context.Context, aggregateID string) (Aggregate, error) {
+	aggregate, _, err := r.LoadWithVersion(ctx, aggregateID)
+	return aggregate, err
+}
+
+// LoadWithVersion implements the Repository interface
+func (r *repository) LoadWithVersion(ctx context.Context, aggregateID string) (Aggregate, int, error) {
+	if r.cache != nil {
+		return r.loadCached(ctx, aggregateID)
+	}
+
+	return r.load(ctx, aggregateID, 0)
+}
+
+// LoadVersion implements the Repository interface
+func (r *repository) LoadVersion(ctx context.Context, aggregateID string, version int) (Aggregate, error) {
+	aggregate, _, err := r.load(ctx, aggregateID, version)
+	return aggregate, err
+}
+
+// LoadAt implements the Repository interface.  Snapshots record when they were taken rather than when their events
+// took place, so the aggregate is always replayed from the beginning of its history.
+func (r *repository) LoadAt(ctx context.Context, aggregateID string, t time.Time) (Aggregate, error) {
 	history, err := r.store.Fetch(ctx, aggregateID, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	entryCount := len(history)
+	at := Time(t)
+	entryCount := 0
+	for entryCount < len(history) && history[entryCount].At <= at {
+		entryCount++
+	}
 	if entryCount == 0 {
-		return nil, errors.New("not found")
+		return nil, NewError(nil, AggregateNotFound, "aggregate id, %v, did not exist at %v", aggregateID, t)
+	}
+
+	r.logf("Loaded %v event(s) for aggregate id, %v, as of %v", entryCount, aggregateID, t)
+	aggregate := r.New()
+	if err := r.apply(aggregate, history[:entryCount]); err != nil {
+		return nil, err
+	}
+
+	return aggregate, nil
+}
+
+// loadCached loads the most recent version of the aggregate, applying only the events saved since it was cached.
+// Returns the aggregate along with the version of the last event applied to it.
+func (r *repository) loadCached(ctx context.Context, aggregateID string) (Aggregate, int, error) {
+	entry, ok := r.cache.get(aggregateID)
+	if !ok {
+		aggregate, version, err := r.load(ctx, aggregateID, 0)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		r.cacheAggregate(aggregate, aggregateID, version)
+		return aggregate, version, nil
 	}
 
-	r.logf("Loaded %v event(s) for aggregate id, %v", entryCount, aggregateID)
 	aggregate := r.New()
+	if err := unmarshalAggregate(aggregate, entry.data); err != nil {
+		r.cache.Invalidate(aggregateID)
+		return nil, 0, NewError(err, InvalidEncoding, "unable to restore cached aggregate id, %v", aggregateID)
+	}
+
+	history, err := r.fetchAfter(ctx, aggregateID, entry.version)
+	if err != nil {
+		r.cache.Invalidate(aggregateID)
+		return nil, 0, err
+	}
+
+	r.logf("Loaded %v event(s) for cached aggregate id, %v, at version %v", len(history), aggregateID, entry.version)
+	if err := r.apply(aggregate, history); err != nil {
+		r.cache.Invalidate(aggregateID)
+		return nil, 0, err
+	}
+
+	if len(history) == 0 {
+		return aggregate, entry.version, nil
+	}
+
+	version := history[len(history)-1].Version
+	r.cacheAggregate(aggregate, aggregateID, version)
+	return aggregate, version, nil
+}
+
+// cacheAggregate adds the aggregate to the cache; failures are logged as the aggregate itself is unaffected
+func (r *repository) cacheAggregate(aggregate Aggregate, aggregateID string, version int) {
+	data, err := marshalAggregate(aggregate)
+	if err != nil {
+		r.logf("Unable to encode aggregate id, %v, for cache - %v", aggregateID, err)
+		return
+	}
+
+	r.cache.put(aggregateID, version, data)
+}
+
+// load loads the aggregate as of the version provided; 0 for the most recent version.  Returns the aggregate along
+// with the version of the last event applied to it.  Snapshots are only saved when loading the most recent version.
+func (r *repository) load(ctx context.Context, aggregateID string, version int) (Aggregate, int, error) {
+	aggregate := r.New()
+
+	snapshot, restored, err := r.restoreSnapshot(ctx, aggregate, aggregateID, version)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var history History
+	if restored {
+		history, err = r.fetchAfter(ctx, aggregateID, snapshot.Version)
+		if err == nil && version > 0 {
+			history = truncate(history, version)
+		}
+	} else {
+		history, err = r.store.Fetch(ctx, aggregateID, version)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entryCount := len(history)
+	if entryCount == 0 && !restored {
+		return nil, 0, NewError(nil, AggregateNotFound, "no events found for aggregate id, %v", aggregateID)
+	}
+
+	r.logf("Loaded %v event(s) for aggregate id, %v", entryCount, aggregateID)
+	if err := r.apply(aggregate, history); err != nil {
+		return nil, 0, err
+	}
+
+	if entryCount == 0 {
+		return aggregate, snapshot.Version, nil
+	}
+
+	if r.snapshots != nil && version == 0 && entryCount >= r.snapshotEvery {
+		r.saveSnapshot(ctx, aggregate, aggregateID, history[entryCount-1].Version)
+	}
+
+	return aggregate, history[entryCount-1].Version, nil
+}
 
+// apply replays the history onto the aggregate
+func (r *repository) apply(aggregate Aggregate, history History) error {
 	for _, record := range history {
 		event, err := r.serializer.Deserialize(record)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		ok := aggregate.On(event)
+		ok, routed := false, false
+		if r.routing {
+			ok, routed = routeEvent(aggregate, event)
+		}
+		if !routed {
+			ok = aggregate.On(event)
+		}
 		if !ok {
 			eventType, _ := EventType(event)
-			return nil, fmt.Errorf(msgUnhandledEvent + " - " + eventType)
+			return NewError(nil, UnhandledEvent, "%v - %v", msgUnhandledEvent, eventType)
+		}
+	}
+
+	return nil
+}
+
+// fetchAfter retrieves the records following version, using the AfterFetcher interface when the Store supports it
+func (r *repository) fetchAfter(ctx context.Context, aggregateID string, version int) (History, error) {
+	if v, ok := r.store.(AfterFetcher); ok {
+		return v.FetchAfter(ctx, aggregateID, version)
+	}
+
+	history, err := r.store.Fetch(ctx, aggregateID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	after := make(History, 0, len(history))
+	for _, record := range history {
+		if record.Version > version {
+			after = append(after, record)
+		}
+	}
+
+	return after, nil
+}
+
+// truncate returns the records of history with a version no greater than version
+func truncate(history History, version int) History {
+	truncated := make(History, 0, len(history))
+	for _, record := range history {
+		if record.Version <= version {
+			truncated = append(truncated, record)
+		}
+	}
+	return truncated
+}
+
+// restoreSnapshot restores the aggregate from the most recent snapshot no newer than version, if snapshots are enabled
+// and one exists
+func (r *repository) restoreSnapshot(ctx context.Context, aggregate Aggregate, aggregateID string, version int) (Snapshot, bool, error) {
+	if r.snapshots == nil {
+		return Snapshot{}, false, nil
+	}
+
+	snapshot, err := r.snapshots.Fetch(ctx, aggregateID, version)
+	if err != nil {
+		if errors.Is(err, ErrSnapshotNotFound) {
+			return Snapshot{}, false, nil
 		}
+		return Snapshot{}, false, err
+	}
+
+	if err := unmarshalAggregate(aggregate, snapshot.Data); err != nil {
+		return Snapshot{}, false, NewError(err, InvalidEncoding, "unable to restore snapshot of aggregate id, %v", aggregateID)
+	}
+
+	r.logf("Restored snapshot of aggregate id, %v, at version %v", aggregateID, snapshot.Version)
+	return snapshot, true, nil
+}
+
+// saveSnapshot saves a snapshot of the aggregate; failures are logged as the aggregate itself is unaffected
+func (r *repository) saveSnapshot(ctx context.Context, aggregate Aggregate, aggregateID string, version int) {
+	data, err := marshalAggregate(aggregate)
+	if err != nil {
+		r.logf("Unable to encode snapshot of aggregate id, %v - %v", aggregateID, err)
+		return
+	}
+
+	err = r.snapshots.Save(ctx, Snapshot{
+		AggregateID: aggregateID,
+		Version:     version,
+		At:          Now(),
+		Data:        data,
+	})
+	if err != nil {
+		r.logf("Unable to save snapshot of aggregate id, %v - %v", aggregateID, err)
+		return
 	}
 
-	return aggregate.(Aggregate), nil
+	r.logf("Saved snapshot of aggregate id, %v, at version %v", aggregateID, version)
+}
+
+// marshalAggregate encodes the aggregate using Snapshotter, which New requires of aggregates snapshotted or cached
+func marshalAggregate(aggregate Aggregate) ([]byte, error) {
+	return aggregate.(Snapshotter).MarshalSnapshot()
+}
+
+// unmarshalAggregate restores the aggregate from data returned by marshalAggregate
+func unmarshalAggregate(aggregate Aggregate, data []byte) error {
+	return aggregate.(Snapshotter).UnmarshalSnapshot(data)
 }
 
 type Option func(registry *repository)
@@ -178,6 +484,43 @@ func WithSerializer(serializer Serializer) Option {
 	}
 }
 
+// WithSnapshots enables snapshots using the SnapshotStore provided.  Load restores the aggregate from its most recent
+// snapshot and saves a new snapshot whenever at least every events were applied beyond it.  The aggregate must
+// implement Snapshotter, as json would lose its unexported fields; New panics otherwise.
+func WithSnapshots(store SnapshotStore, every int) Option {
+	return func(registry *repository) {
+		if every < 1 {
+			every = 1
+		}
+		registry.snapshots = store
+		registry.snapshotEvery = every
+	}
+}
+
+// WithCache caches the aggregates loaded by Load; see Cache.  The aggregate must implement Snapshotter, as json would
+// lose its unexported fields; New panics otherwise.
+func WithCache(cache *Cache) Option {
+	return func(registry *repository) {
+		registry.cache = cache
+	}
+}
+
+// WithRouting applies events to the aggregate using Route rather than On, and verifies at Bind that the aggregate has
+// a method to handle each event; see Route.  Events the aggregate has no method for are passed to On.
+func WithRouting() Option {
+	return func(registry *repository) {
+		registry.routing = true
+	}
+}
+
+// WithTypeRegistry specifies the TypeRegistry that Bind registers events with; the default JSONSerializer shares the
+// registry, including its aliases
+func WithTypeRegistry(types *TypeRegistry) Option {
+	return func(registry *repository) {
+		registry.types = types
+	}
+}
+
 func WithDebug(w io.Writer) Option {
 	return func(registry *repository) {
 		registry.debug = true
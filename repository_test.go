@@ -2,6 +2,7 @@ package eventsource_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -55,6 +56,12 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, &Entity{}, aggregate)
 }
 
+func TestBindNil(t *testing.T) {
+	repository := eventsource.New(&Entity{})
+	err := repository.Bind(EntityCreated{}, nil)
+	assert.True(t, errors.Is(err, eventsource.ErrUnboundEventType))
+}
+
 func TestRegistry(t *testing.T) {
 	ctx := context.Background()
 	id := "123"
@@ -164,3 +171,115 @@ func TestAt(t *testing.T) {
 	assert.NotZero(t, org.CreatedAt)
 	assert.NotZero(t, org.UpdatedAt)
 }
+
+func TestConflict(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	registry := eventsource.New(&Entity{})
+	registry.Bind(EntityCreated{}, EntityNameSet{})
+
+	err := registry.Save(ctx,
+		&EntityCreated{
+			Model: eventsource.Model{ID: id, Version: 1},
+		},
+		&EntityNameSet{
+			Model: eventsource.Model{ID: id, Version: 2},
+			Name:  "Jones",
+		},
+	)
+	assert.Nil(t, err)
+
+	// Test - A second writer that loaded version 1 should be rejected
+
+	err = registry.Save(ctx, &EntityNameSet{
+		Model: eventsource.Model{ID: id, Version: 2},
+		Name:  "Sarah",
+	})
+	assert.NotNil(t, err)
+
+	v, ok := err.(eventsource.Error)
+	assert.True(t, ok)
+	assert.Equal(t, eventsource.DuplicateVersion, v.Code())
+
+	// Test - Writers save against the version they loaded
+
+	_, version, err := registry.LoadWithVersion(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, version)
+
+	err = registry.SaveVersion(ctx, version, &EntityNameSet{
+		Model: eventsource.Model{ID: id, Version: 3},
+		Name:  "Sarah",
+	})
+	assert.Nil(t, err)
+
+	// Test - A writer that loaded an older version is rejected even if its event versions do not collide
+
+	err = registry.SaveVersion(ctx, version, &EntityNameSet{
+		Model: eventsource.Model{ID: id, Version: 4},
+		Name:  "Jane",
+	})
+	assert.True(t, errors.Is(err, eventsource.ErrDuplicateVersion))
+
+	aggregate, err := registry.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Sarah", aggregate.(*Entity).Name)
+}
+
+func TestLoadVersion(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	registry := eventsource.New(&Entity{})
+	registry.Bind(EntityCreated{}, EntityNameSet{})
+
+	err := registry.Save(ctx,
+		&EntityCreated{Model: eventsource.Model{ID: id, Version: 1}},
+		&EntityNameSet{Model: eventsource.Model{ID: id, Version: 2}, Name: "Jones"},
+		&EntityNameSet{Model: eventsource.Model{ID: id, Version: 3}, Name: "Sarah"},
+	)
+	assert.Nil(t, err)
+
+	v, err := registry.LoadVersion(ctx, id, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "Jones", v.(*Entity).Name)
+	assert.Equal(t, 2, v.(*Entity).Version)
+
+	v, err = registry.LoadVersion(ctx, id, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "Sarah", v.(*Entity).Name)
+}
+
+func TestLoadAt(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	registry := eventsource.New(&Entity{})
+	registry.Bind(EntityCreated{}, EntityNameSet{})
+
+	err := registry.Save(ctx,
+		&EntityCreated{Model: eventsource.Model{ID: id, Version: 1, At: time.Unix(10, 0)}},
+		&EntityNameSet{Model: eventsource.Model{ID: id, Version: 2, At: time.Unix(20, 0)}, Name: "Jones"},
+		&EntityNameSet{Model: eventsource.Model{ID: id, Version: 3, At: time.Unix(30, 0)}, Name: "Sarah"},
+	)
+	assert.Nil(t, err)
+
+	v, err := registry.LoadAt(ctx, id, time.Unix(25, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, "Jones", v.(*Entity).Name)
+	assert.Equal(t, 2, v.(*Entity).Version)
+
+	v, err = registry.LoadAt(ctx, id, time.Unix(30, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, "Sarah", v.(*Entity).Name)
+
+	// Test - The aggregate did not yet exist
+
+	_, err = registry.LoadAt(ctx, id, time.Unix(5, 0))
+	assert.NotNil(t, err)
+
+	e, ok := err.(eventsource.Error)
+	assert.True(t, ok)
+	assert.Equal(t, eventsource.AggregateNotFound, e.Code())
+}
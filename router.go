@@ -0,0 +1,128 @@
+package eventsource
+
+import (
+	"reflect"
+	"sync"
+)
+
+// routePrefix prefixes the Go type name of the event to form the name of the method handling it
+const routePrefix = "On"
+
+var (
+	boolType = reflect.TypeOf(true)
+
+	// routes caches the route of each aggregate and event type pair
+	routes = &sync.Map{}
+)
+
+type routeKey struct {
+	aggregate reflect.Type
+	event     reflect.Type
+}
+
+type route struct {
+	method reflect.Method
+	ptr    bool
+	found  bool
+}
+
+// Route calls the method of the aggregate that handles the event, named On followed by the Go type name of the event
+// and accepting the event, or a pointer to it, as its only argument e.g.
+//
+//	func (u *User) OnUserCreated(event *UserCreated) { ... }
+//
+// The method may return nothing, or a bool reporting whether the event was handled.  Route allows aggregates to
+// replace the type switch within On:
+//
+//	func (u *User) On(event eventsource.Event) bool {
+//		return eventsource.Route(u, event)
+//	}
+//
+// Returns false if the aggregate has no method handling the event.  See WithRouting to verify at Bind that each
+// event has a handler.
+func Route(aggregate Aggregate, event Event) bool {
+	handled, _ := routeEvent(aggregate, event)
+	return handled
+}
+
+// routeEvent routes the event to the aggregate; found reports whether the aggregate has a method for the event
+func routeEvent(aggregate Aggregate, event Event) (handled, found bool) {
+	v := reflect.ValueOf(event)
+	r := lookupRoute(reflect.TypeOf(aggregate), v.Type())
+	if !r.found {
+		return false, false
+	}
+
+	switch {
+	case r.ptr && v.Kind() != reflect.Ptr:
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr
+	case !r.ptr && v.Kind() == reflect.Ptr:
+		v = v.Elem()
+	}
+
+	out := r.method.Func.Call([]reflect.Value{reflect.ValueOf(aggregate), v})
+	if len(out) == 1 {
+		return out[0].Bool(), true
+	}
+	return true, true
+}
+
+// ValidateRoutes returns an Error with the UnhandledEvent code if the aggregate has no method to Route any of the
+// events to
+func ValidateRoutes(aggregate Aggregate, events ...Event) error {
+	aggregateType := reflect.TypeOf(aggregate)
+	for _, event := range events {
+		eventType := reflect.TypeOf(event)
+		if r := lookupRoute(aggregateType, eventType); !r.found {
+			return NewError(nil, UnhandledEvent, "%v has no method, %v, to handle %v", aggregateType, methodName(eventType), eventType)
+		}
+	}
+
+	return nil
+}
+
+func lookupRoute(aggregateType, eventType reflect.Type) route {
+	key := routeKey{aggregate: aggregateType, event: eventType}
+	if v, ok := routes.Load(key); ok {
+		return v.(route)
+	}
+
+	r := findRoute(aggregateType, eventType)
+	routes.Store(key, r)
+	return r
+}
+
+func findRoute(aggregateType, eventType reflect.Type) route {
+	if eventType.Kind() == reflect.Ptr {
+		eventType = eventType.Elem()
+	}
+
+	method, ok := aggregateType.MethodByName(methodName(eventType))
+	if !ok {
+		return route{}
+	}
+
+	// the receiver is the first argument of method.Type
+	t := method.Type
+	if t.NumIn() != 2 || t.NumOut() > 1 || (t.NumOut() == 1 && t.Out(0) != boolType) {
+		return route{}
+	}
+
+	switch t.In(1) {
+	case eventType:
+		return route{method: method, found: true}
+	case reflect.PtrTo(eventType):
+		return route{method: method, ptr: true, found: true}
+	default:
+		return route{}
+	}
+}
+
+func methodName(eventType reflect.Type) string {
+	if eventType.Kind() == reflect.Ptr {
+		eventType = eventType.Elem()
+	}
+	return routePrefix + eventType.Name()
+}
@@ -0,0 +1,82 @@
+package eventsource_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/savaki/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+type Routed struct {
+	ID      string
+	Version int
+	Name    string
+}
+
+func (r *Routed) On(event eventsource.Event) bool {
+	return eventsource.Route(r, event)
+}
+
+func (r *Routed) OnEntityCreated(event *EntityCreated) {
+	r.ID = event.ID
+	r.Version = event.Version
+}
+
+func (r *Routed) OnEntityNameSet(event EntityNameSet) bool {
+	r.Name = event.Name
+	r.Version = event.Version
+	return event.Name != ""
+}
+
+func TestRoute(t *testing.T) {
+	aggregate := &Routed{}
+
+	ok := eventsource.Route(aggregate, &EntityCreated{Model: eventsource.Model{ID: "123", Version: 1}})
+	assert.True(t, ok)
+	assert.Equal(t, "123", aggregate.ID)
+
+	ok = eventsource.Route(aggregate, EntityNameSet{Model: eventsource.Model{Version: 2}, Name: "Jones"})
+	assert.True(t, ok)
+	assert.Equal(t, "Jones", aggregate.Name)
+	assert.Equal(t, 2, aggregate.Version)
+
+	ok = eventsource.Route(aggregate, &EntityNameSet{Model: eventsource.Model{Version: 3}})
+	assert.False(t, ok, "expected the handler result to be returned")
+
+	ok = eventsource.Route(aggregate, &EntityRenamed{})
+	assert.False(t, ok)
+}
+
+func TestValidateRoutes(t *testing.T) {
+	err := eventsource.ValidateRoutes(&Routed{}, EntityCreated{}, &EntityNameSet{})
+	assert.Nil(t, err)
+
+	err = eventsource.ValidateRoutes(&Routed{}, EntityCreated{}, EntityRenamed{})
+	assert.True(t, errors.Is(err, eventsource.ErrUnhandledEvent))
+	assert.Contains(t, err.Error(), "OnEntityRenamed")
+}
+
+func TestWithRouting(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	registry := eventsource.New(&Routed{}, eventsource.WithRouting())
+
+	err := registry.Bind(EntityCreated{}, EntityNameSet{})
+	assert.Nil(t, err)
+
+	err = registry.Bind(EntityRenamed{})
+	assert.True(t, errors.Is(err, eventsource.ErrUnhandledEvent))
+
+	err = registry.Save(ctx,
+		&EntityCreated{Model: eventsource.Model{ID: id, Version: 1}},
+		&EntityNameSet{Model: eventsource.Model{ID: id, Version: 2}, Name: "Jones"},
+	)
+	assert.Nil(t, err)
+
+	v, err := registry.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, &Routed{ID: id, Version: 2, Name: "Jones"}, v)
+}
@@ -12,21 +12,36 @@ type Serializer interface {
 }
 
 type jsonEvent struct {
-	Type string          `json:"t"`
-	Data json.RawMessage `json:"d"`
+	Type    string          `json:"t"`
+	Version int             `json:"v,omitempty"`
+	Data    json.RawMessage `json:"d"`
 }
 
 type jsonSerializer struct {
-	eventTypes map[string]reflect.Type
+	types    *TypeRegistry
+	upcaster *Upcaster
 }
 
-func (j *jsonSerializer) Bind(events ...Event) error {
-	for _, event := range events {
-		eventType, t := EventType(event)
-		j.eventTypes[eventType] = t
+// JSONOption provides optional configuration to the JSONSerializer
+type JSONOption func(*jsonSerializer)
+
+// WithJSONTypeRegistry specifies the TypeRegistry events are bound to, allowing the registry, along with its aliases,
+// to be shared
+func WithJSONTypeRegistry(types *TypeRegistry) JSONOption {
+	return func(j *jsonSerializer) {
+		j.types = types
+	}
+}
+
+// WithUpcaster upcasts stored events to the current schema of the bound event types prior to unmarshalling them
+func WithUpcaster(upcaster *Upcaster) JSONOption {
+	return func(j *jsonSerializer) {
+		j.upcaster = upcaster
 	}
+}
 
-	return nil
+func (j *jsonSerializer) Bind(events ...Event) error {
+	return j.types.Register(events...)
 }
 
 func (j *jsonSerializer) Serialize(v Event) (Record, error) {
@@ -37,19 +52,30 @@ func (j *jsonSerializer) Serialize(v Event) (Record, error) {
 		return Record{}, err
 	}
 
-	data, err = json.Marshal(jsonEvent{
+	wrapper := jsonEvent{
 		Type: eventType,
 		Data: json.RawMessage(data),
-	})
+	}
+	if sv, ok := v.(SchemaVersioner); ok {
+		wrapper.Version = sv.SchemaVersion()
+	}
+
+	data, err = json.Marshal(wrapper)
 	if err != nil {
 		return Record{}, NewError(err, InvalidEncoding, "unable to encode event")
 	}
 
-	return Record{
+	record := Record{
 		Version: v.EventVersion(),
 		At:      Time(v.EventAt()),
+		Type:    eventType,
 		Data:    data,
-	}, nil
+	}
+	if m, ok := v.(MetadataProvider); ok {
+		record.Metadata = m.EventMetadata()
+	}
+
+	return record, nil
 }
 
 func (j *jsonSerializer) Deserialize(record Record) (Event, error) {
@@ -59,7 +85,15 @@ func (j *jsonSerializer) Deserialize(record Record) (Event, error) {
 		return nil, NewError(err, InvalidEncoding, "unable to unmarshal event")
 	}
 
-	t, ok := j.eventTypes[wrapper.Type]
+	if j.upcaster != nil {
+		eventType, version, data, err := j.upcaster.Upcast(wrapper.Type, wrapper.Version, wrapper.Data)
+		if err != nil {
+			return nil, err
+		}
+		wrapper.Type, wrapper.Version, wrapper.Data = eventType, version, data
+	}
+
+	t, ok := j.types.Lookup(wrapper.Type)
 	if !ok {
 		return nil, NewError(err, UnboundEventType, "unbound event type, %v", wrapper.Type)
 	}
@@ -70,11 +104,21 @@ func (j *jsonSerializer) Deserialize(record Record) (Event, error) {
 		return nil, NewError(err, InvalidEncoding, "unable to unmarshal event data into %#v", v)
 	}
 
+	if m, ok := v.(MetadataReceiver); ok && record.Metadata != nil {
+		m.SetEventMetadata(record.Metadata)
+	}
+
 	return v.(Event), nil
 }
 
-func JSONSerializer() Serializer {
-	return &jsonSerializer{
-		eventTypes: map[string]reflect.Type{},
+func JSONSerializer(opts ...JSONOption) Serializer {
+	j := &jsonSerializer{
+		types: NewTypeRegistry(),
 	}
+
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	return j
 }
@@ -0,0 +1,177 @@
+// Package compression provides an eventsource.Serializer decorator that compresses the encoded events of another
+// Serializer
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/savaki/eventsource"
+)
+
+// Algorithm identifies a compression algorithm; the Algorithm is written as the first byte of compressed data
+type Algorithm byte
+
+// Header bytes 0x01-0x03 never begin the output of the JSON, MessagePack, or protobuf serializers, which allows
+// records written without compression to be read back unchanged
+const (
+	Gzip   Algorithm = 0x01
+	Snappy Algorithm = 0x02
+	Zstd   Algorithm = 0x03
+)
+
+const (
+	// DefaultThreshold is the size, in bytes, at or above which data is compressed
+	DefaultThreshold = 512
+)
+
+// Serializer wraps an eventsource.Serializer and compresses Record.Data of at least the threshold size
+type Serializer struct {
+	serializer eventsource.Serializer
+	algorithm  Algorithm
+	threshold  int
+
+	once    sync.Once
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+	err     error
+}
+
+// Option provides optional configuration to the Serializer
+type Option func(*Serializer)
+
+// WithAlgorithm specifies the algorithm used to compress data; defaults to Gzip.  Data compressed with any Algorithm
+// can be read regardless of this setting.
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(s *Serializer) {
+		s.algorithm = algorithm
+	}
+}
+
+// WithThreshold specifies the size, in bytes, at or above which data is compressed; defaults to DefaultThreshold
+func WithThreshold(threshold int) Option {
+	return func(s *Serializer) {
+		s.threshold = threshold
+	}
+}
+
+// New returns a Serializer that compresses the data produced by serializer
+func New(serializer eventsource.Serializer, opts ...Option) *Serializer {
+	s := &Serializer{
+		serializer: serializer,
+		algorithm:  Gzip,
+		threshold:  DefaultThreshold,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Bind implements the eventsource.Serializer interface
+func (s *Serializer) Bind(events ...eventsource.Event) error {
+	return s.serializer.Bind(events...)
+}
+
+// Serialize implements the eventsource.Serializer interface.  Data is left uncompressed if it is smaller than the
+// threshold or if compression fails to reduce its size.
+func (s *Serializer) Serialize(event eventsource.Event) (eventsource.Record, error) {
+	record, err := s.serializer.Serialize(event)
+	if err != nil {
+		return eventsource.Record{}, err
+	}
+
+	if len(record.Data) < s.threshold {
+		return record, nil
+	}
+
+	data, err := s.compress(record.Data)
+	if err != nil {
+		return eventsource.Record{}, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to compress event")
+	}
+
+	if len(data) < len(record.Data) {
+		record.Data = data
+	}
+
+	return record, nil
+}
+
+// Deserialize implements the eventsource.Serializer interface
+func (s *Serializer) Deserialize(record eventsource.Record) (eventsource.Event, error) {
+	if len(record.Data) > 0 {
+		switch algorithm := Algorithm(record.Data[0]); algorithm {
+		case Gzip, Snappy, Zstd:
+			data, err := s.decompress(algorithm, record.Data[1:])
+			if err != nil {
+				return nil, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to decompress event")
+			}
+			record.Data = data
+		}
+	}
+
+	return s.serializer.Deserialize(record)
+}
+
+func (s *Serializer) compress(data []byte) ([]byte, error) {
+	switch s.algorithm {
+	case Snappy:
+		return append([]byte{byte(Snappy)}, s2.EncodeSnappy(nil, data)...), nil
+
+	case Zstd:
+		if err := s.initZstd(); err != nil {
+			return nil, err
+		}
+		return s.encoder.EncodeAll(data, []byte{byte(Zstd)}), nil
+
+	default:
+		buf := bytes.NewBuffer([]byte{byte(Gzip)})
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+func (s *Serializer) decompress(algorithm Algorithm, data []byte) ([]byte, error) {
+	switch algorithm {
+	case Snappy:
+		return s2.Decode(nil, data)
+
+	case Zstd:
+		if err := s.initZstd(); err != nil {
+			return nil, err
+		}
+		return s.decoder.DecodeAll(data, nil)
+
+	default:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+}
+
+// initZstd lazily creates the zstd encoder and decoder, which are safe for concurrent use via EncodeAll and DecodeAll
+func (s *Serializer) initZstd() error {
+	s.once.Do(func() {
+		s.encoder, s.err = zstd.NewWriter(nil)
+		if s.err != nil {
+			return
+		}
+		s.decoder, s.err = zstd.NewReader(nil)
+	})
+	return s.err
+}
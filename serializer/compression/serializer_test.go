@@ -0,0 +1,154 @@
+package compression_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/savaki/eventsource"
+	"github.com/savaki/eventsource/serializer/compression"
+	"github.com/stretchr/testify/assert"
+)
+
+type Entity struct {
+	ID          string
+	Version     int
+	Description string
+}
+
+type EntityDescriptionSet struct {
+	eventsource.Model
+	Description string
+}
+
+func (e *Entity) On(event eventsource.Event) bool {
+	switch v := event.(type) {
+	case *EntityDescriptionSet:
+		e.ID = v.ID
+		e.Version = v.Version
+		e.Description = v.Description
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+func TestSerializer(t *testing.T) {
+	long := EntityDescriptionSet{
+		Model:       eventsource.Model{ID: "123", Version: 1},
+		Description: strings.Repeat("abc", 1000),
+	}
+	short := EntityDescriptionSet{
+		Model:       eventsource.Model{ID: "123", Version: 2},
+		Description: "abc",
+	}
+
+	for name, algorithm := range map[string]compression.Algorithm{
+		"gzip":   compression.Gzip,
+		"snappy": compression.Snappy,
+		"zstd":   compression.Zstd,
+	} {
+		t.Run(name, func(t *testing.T) {
+			serializer := compression.New(eventsource.JSONSerializer(), compression.WithAlgorithm(algorithm))
+			serializer.Bind(EntityDescriptionSet{})
+
+			record, err := serializer.Serialize(long)
+			assert.Nil(t, err)
+			assert.Equal(t, byte(algorithm), record.Data[0])
+			assert.True(t, len(record.Data) < len(long.Description))
+
+			v, err := serializer.Deserialize(record)
+			assert.Nil(t, err)
+			assert.Equal(t, &long, v)
+
+			// Test - Data below the threshold is not compressed
+
+			record, err = serializer.Serialize(short)
+			assert.Nil(t, err)
+			assert.Equal(t, byte('{'), record.Data[0])
+
+			v, err = serializer.Deserialize(record)
+			assert.Nil(t, err)
+			assert.Equal(t, &short, v)
+		})
+	}
+}
+
+func TestSerializerThreshold(t *testing.T) {
+	event := EntityDescriptionSet{
+		Model:       eventsource.Model{ID: "123", Version: 1},
+		Description: strings.Repeat("abc", 1000),
+	}
+
+	serializer := compression.New(eventsource.JSONSerializer(), compression.WithThreshold(1<<20))
+	record, err := serializer.Serialize(event)
+	assert.Nil(t, err)
+	assert.Equal(t, byte('{'), record.Data[0])
+}
+
+func TestSerializerMixedAlgorithms(t *testing.T) {
+	event := EntityDescriptionSet{
+		Model:       eventsource.Model{ID: "123", Version: 1},
+		Description: strings.Repeat("abc", 1000),
+	}
+
+	record, err := compression.New(eventsource.JSONSerializer(), compression.WithAlgorithm(compression.Zstd)).Serialize(event)
+	assert.Nil(t, err)
+
+	serializer := compression.New(eventsource.JSONSerializer(), compression.WithAlgorithm(compression.Snappy))
+	serializer.Bind(EntityDescriptionSet{})
+
+	v, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+	assert.Equal(t, &event, v)
+}
+
+func TestSerializerLegacy(t *testing.T) {
+	event := EntityDescriptionSet{
+		Model:       eventsource.Model{ID: "123", Version: 1},
+		Description: strings.Repeat("abc", 1000),
+	}
+
+	legacy := eventsource.JSONSerializer()
+	record, err := legacy.Serialize(event)
+	assert.Nil(t, err)
+
+	serializer := compression.New(eventsource.JSONSerializer())
+	serializer.Bind(EntityDescriptionSet{})
+
+	v, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+	assert.Equal(t, &event, v)
+}
+
+func TestSerializerCorrupt(t *testing.T) {
+	serializer := compression.New(eventsource.JSONSerializer())
+	_, err := serializer.Deserialize(eventsource.Record{Data: []byte{byte(compression.Gzip), 1, 2, 3}})
+	assert.NotNil(t, err)
+
+	v, ok := err.(eventsource.Error)
+	assert.True(t, ok)
+	assert.Equal(t, eventsource.InvalidEncoding, v.Code())
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+	description := strings.Repeat("abc", 1000)
+
+	serializer := compression.New(eventsource.JSONSerializer(), compression.WithAlgorithm(compression.Snappy))
+	repository := eventsource.New(&Entity{}, eventsource.WithSerializer(serializer))
+	repository.Bind(EntityDescriptionSet{})
+
+	err := repository.Save(ctx, &EntityDescriptionSet{
+		Model:       eventsource.Model{ID: id, Version: 1},
+		Description: description,
+	})
+	assert.Nil(t, err)
+
+	v, err := repository.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, &Entity{ID: id, Version: 1, Description: description}, v)
+}
@@ -0,0 +1,79 @@
+package encryption
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrKeyNotFound is returned by a KeyProvider when no key exists with the requested id
+	ErrKeyNotFound = errors.New("key not found")
+)
+
+// KeyProvider provides the keys used to encrypt and decrypt records.  Keys must be 16, 24, or 32 bytes long to select
+// AES-128, AES-192, or AES-256.
+type KeyProvider interface {
+	// CurrentKey returns the id and value of the key used to encrypt new records
+	CurrentKey() (keyID string, key []byte, err error)
+
+	// Key returns the key with the specified id, which may be the current key or a retired one; returns
+	// ErrKeyNotFound if no such key exists
+	Key(keyID string) ([]byte, error)
+}
+
+// Keyring provides an in-memory KeyProvider that retains retired keys for decryption
+type Keyring struct {
+	mux     *sync.Mutex
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyring returns a Keyring whose current key is key
+func NewKeyring(keyID string, key []byte) *Keyring {
+	return &Keyring{
+		mux:     &sync.Mutex{},
+		current: keyID,
+		keys:    map[string][]byte{keyID: key},
+	}
+}
+
+// Rotate makes key the current key; the previous key is retired and remains available for decryption
+func (k *Keyring) Rotate(keyID string, key []byte) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	k.current = keyID
+	k.keys[keyID] = key
+}
+
+// Remove discards a retired key; records encrypted with it can no longer be decrypted.  The current key can not be
+// removed.
+func (k *Keyring) Remove(keyID string) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	if keyID != k.current {
+		delete(k.keys, keyID)
+	}
+}
+
+// CurrentKey implements the KeyProvider interface
+func (k *Keyring) CurrentKey() (string, []byte, error) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	return k.current, k.keys[k.current], nil
+}
+
+// Key implements the KeyProvider interface
+func (k *Keyring) Key(keyID string) ([]byte, error) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return key, nil
+}
@@ -0,0 +1,85 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+
+	"github.com/savaki/eventsource"
+)
+
+const (
+	// reencryptBatchSize is the number of records read from the source at a time
+	reencryptBatchSize = 100
+)
+
+// ReencryptStore copies every record from the log of src into dst, re-encrypting each with the current key of the
+// Serializer.  As stores are append-only, rotation is performed by copying into a new store and switching over to it
+// once the copy completes; records encrypted with retired keys may then be discarded along with src.
+//
+// ReencryptStore returns the position of the last record copied, which may be passed as startingPosition to resume
+// an interrupted copy or to copy records appended to src since the previous call.  Should the process crash before
+// the position is recorded, the copy may be restarted from any earlier position, e.g. 0, as records dst already holds
+// are skipped until the first record yet to be copied.
+func ReencryptStore(ctx context.Context, s *Serializer, src eventsource.Reader, dst eventsource.Store, startingPosition int64) (int64, error) {
+	position := startingPosition
+	resuming := true
+	for {
+		records, err := src.Read(ctx, position, reencryptBatchSize)
+		if err != nil {
+			return position, err
+		}
+		if len(records) == 0 {
+			return position, nil
+		}
+
+		for _, record := range records {
+			if resuming {
+				ok, err := copied(ctx, dst, record)
+				if err != nil {
+					return position, err
+				}
+				if ok {
+					position = record.Position
+					continue
+				}
+				resuming = false
+			}
+
+			reencrypted, err := s.Reencrypt(record.Record)
+			if err != nil {
+				return position, err
+			}
+
+			if err := dst.Save(ctx, record.AggregateID, reencrypted); err != nil {
+				return position, err
+			}
+
+			position = record.Position
+		}
+	}
+}
+
+// copied returns true if dst already holds the version of the record, i.e. it was copied by an earlier call.  As
+// records are copied in order, only those preceding the first record not yet copied need be checked.
+func copied(ctx context.Context, dst eventsource.Store, record eventsource.StreamRecord) (bool, error) {
+	var history eventsource.History
+	var err error
+	if v, ok := dst.(eventsource.AfterFetcher); ok {
+		history, err = v.FetchAfter(ctx, record.AggregateID, record.Version-1)
+	} else {
+		history, err = dst.Fetch(ctx, record.AggregateID, 0)
+	}
+	if errors.Is(err, eventsource.ErrAggregateNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, h := range history {
+		if h.Version == record.Version {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,119 @@
+package encryption_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/savaki/eventsource"
+	"github.com/savaki/eventsource/serializer/encryption"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingStore fails every Save once limit records have been saved, simulating a crash
+type failingStore struct {
+	*eventsource.MemoryStore
+	limit int
+}
+
+func (f *failingStore) Save(ctx context.Context, aggregateID string, records ...eventsource.Record) error {
+	if f.limit <= 0 {
+		return errors.New("crashed")
+	}
+	f.limit--
+	return f.MemoryStore.Save(ctx, aggregateID, records...)
+}
+
+func TestReencryptStore(t *testing.T) {
+	ctx := context.Background()
+
+	keyring := encryption.NewKeyring("key-1", key1)
+	serializer := encryption.New(eventsource.JSONSerializer(), keyring)
+
+	src := eventsource.NewMemoryStore()
+	repository := eventsource.New(&Entity{},
+		eventsource.WithStore(src),
+		eventsource.WithSerializer(serializer),
+	)
+	repository.Bind(EntitySSNSet{})
+
+	for _, id := range []string{"a", "b"} {
+		err := repository.Save(ctx,
+			&EntitySSNSet{Model: eventsource.Model{ID: id, Version: 1}, SSN: "1"},
+			&EntitySSNSet{Model: eventsource.Model{ID: id, Version: 2}, SSN: "2"},
+		)
+		assert.Nil(t, err)
+	}
+
+	keyring.Rotate("key-2", key2)
+
+	dst := eventsource.NewMemoryStore()
+	position, err := encryption.ReencryptStore(ctx, serializer, src, dst, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), position)
+
+	records, err := dst.Read(ctx, 0, 10)
+	assert.Nil(t, err)
+	assert.Len(t, records, 4)
+	for _, record := range records {
+		keyID, _ := encryption.KeyID(record.Data)
+		assert.Equal(t, "key-2", keyID)
+	}
+
+	// Test - Once copied, the retired key is no longer needed
+
+	keyring.Remove("key-1")
+
+	repository = eventsource.New(&Entity{},
+		eventsource.WithStore(dst),
+		eventsource.WithSerializer(serializer),
+	)
+	repository.Bind(EntitySSNSet{})
+
+	v, err := repository.Load(ctx, "b")
+	assert.Nil(t, err)
+	assert.Equal(t, &Entity{ID: "b", Version: 2, SSN: "2"}, v)
+
+	// Test - Resuming from the returned position copies nothing further
+
+	position, err = encryption.ReencryptStore(ctx, serializer, src, dst, position)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), position)
+}
+
+func TestReencryptStore_Restart(t *testing.T) {
+	ctx := context.Background()
+
+	keyring := encryption.NewKeyring("key-1", key1)
+	serializer := encryption.New(eventsource.JSONSerializer(), keyring)
+
+	src := eventsource.NewMemoryStore()
+	repository := eventsource.New(&Entity{},
+		eventsource.WithStore(src),
+		eventsource.WithSerializer(serializer),
+	)
+	repository.Bind(EntitySSNSet{})
+
+	for version := 1; version <= 3; version++ {
+		err := repository.Save(ctx, &EntitySSNSet{Model: eventsource.Model{ID: "a", Version: version}, SSN: "1"})
+		assert.Nil(t, err)
+	}
+
+	keyring.Rotate("key-2", key2)
+
+	dst := &failingStore{MemoryStore: eventsource.NewMemoryStore(), limit: 2}
+	_, err := encryption.ReencryptStore(ctx, serializer, src, dst, 0)
+	assert.NotNil(t, err)
+
+	// Test - Restarting from the beginning skips the records already copied
+
+	dst.limit = 10
+	position, err := encryption.ReencryptStore(ctx, serializer, src, dst, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), position)
+	assert.Equal(t, 9, dst.limit)
+
+	history, err := dst.Fetch(ctx, "a", 0)
+	assert.Nil(t, err)
+	assert.Len(t, history, 3)
+}
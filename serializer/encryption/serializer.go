@@ -0,0 +1,170 @@
+// Package encryption provides an eventsource.Serializer decorator that encrypts the encoded events of another
+// Serializer using AES-GCM
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"github.com/savaki/eventsource"
+)
+
+// header is the first byte of encrypted data.  It is distinct from the first byte produced by the JSON, MessagePack,
+// protobuf and compression serializers, which allows records written prior to encryption to be read back unchanged.
+const header byte = 0x04
+
+var (
+	errInvalidKeyID = errors.New("key id must be between 1 and 255 bytes")
+	errTruncated    = errors.New("encrypted data truncated")
+)
+
+// Serializer wraps an eventsource.Serializer and encrypts Record.Data.  Encrypted data holds, in order, the header
+// byte, the length of the key id, the key id, the nonce, and the sealed data; the header and key id are
+// authenticated along with the data.
+type Serializer struct {
+	serializer eventsource.Serializer
+	keys       KeyProvider
+}
+
+// New returns a Serializer that encrypts the data produced by serializer with keys from keys
+func New(serializer eventsource.Serializer, keys KeyProvider) *Serializer {
+	return &Serializer{
+		serializer: serializer,
+		keys:       keys,
+	}
+}
+
+// Bind implements the eventsource.Serializer interface
+func (s *Serializer) Bind(events ...eventsource.Event) error {
+	return s.serializer.Bind(events...)
+}
+
+// Serialize implements the eventsource.Serializer interface
+func (s *Serializer) Serialize(event eventsource.Event) (eventsource.Record, error) {
+	record, err := s.serializer.Serialize(event)
+	if err != nil {
+		return eventsource.Record{}, err
+	}
+
+	record.Data, err = s.encrypt(record.Data)
+	if err != nil {
+		return eventsource.Record{}, err
+	}
+
+	return record, nil
+}
+
+// Deserialize implements the eventsource.Serializer interface; records that were not encrypted are passed to the
+// underlying Serializer unchanged
+func (s *Serializer) Deserialize(record eventsource.Record) (eventsource.Event, error) {
+	data, err := s.decrypt(record.Data)
+	if err != nil {
+		return nil, err
+	}
+	record.Data = data
+
+	return s.serializer.Deserialize(record)
+}
+
+// Reencrypt returns the record with its data encrypted by the current key.  Records already encrypted by the current
+// key are returned unchanged, while records not yet encrypted are encrypted.
+func (s *Serializer) Reencrypt(record eventsource.Record) (eventsource.Record, error) {
+	keyID, _, err := s.keys.CurrentKey()
+	if err != nil {
+		return eventsource.Record{}, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to retrieve current key")
+	}
+
+	if id, ok := KeyID(record.Data); ok && id == keyID {
+		return record, nil
+	}
+
+	data, err := s.decrypt(record.Data)
+	if err != nil {
+		return eventsource.Record{}, err
+	}
+
+	data, err = s.encrypt(data)
+	if err != nil {
+		return eventsource.Record{}, err
+	}
+	record.Data = data
+
+	return record, nil
+}
+
+// KeyID returns the id of the key data was encrypted with; false if data is not encrypted
+func KeyID(data []byte) (string, bool) {
+	if len(data) < 2 || data[0] != header || len(data) < 2+int(data[1]) {
+		return "", false
+	}
+
+	return string(data[2 : 2+int(data[1])]), true
+}
+
+func (s *Serializer) encrypt(data []byte) ([]byte, error) {
+	keyID, key, err := s.keys.CurrentKey()
+	if err != nil {
+		return nil, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to retrieve current key")
+	}
+	if len(keyID) == 0 || len(keyID) > 255 {
+		return nil, eventsource.NewError(errInvalidKeyID, eventsource.InvalidEncoding, "invalid key id, %v", keyID)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, eventsource.NewError(err, eventsource.InvalidEncoding, "invalid key, %v", keyID)
+	}
+
+	prefix := make([]byte, 0, 2+len(keyID)+aead.NonceSize()+len(data)+aead.Overhead())
+	prefix = append(prefix, header, byte(len(keyID)))
+	prefix = append(prefix, keyID...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to generate nonce")
+	}
+
+	return aead.Seal(append(prefix, nonce...), nonce, data, prefix), nil
+}
+
+func (s *Serializer) decrypt(data []byte) ([]byte, error) {
+	keyID, ok := KeyID(data)
+	if !ok {
+		return data, nil
+	}
+
+	key, err := s.keys.Key(keyID)
+	if err != nil {
+		return nil, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to retrieve key, %v", keyID)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, eventsource.NewError(err, eventsource.InvalidEncoding, "invalid key, %v", keyID)
+	}
+
+	prefix := data[:2+len(keyID)]
+	sealed := data[len(prefix):]
+	if len(sealed) < aead.NonceSize() {
+		return nil, eventsource.NewError(errTruncated, eventsource.InvalidEncoding, "unable to decrypt event")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], prefix)
+	if err != nil {
+		return nil, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to decrypt event")
+	}
+
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
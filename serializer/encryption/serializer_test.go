@@ -0,0 +1,181 @@
+package encryption_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/savaki/eventsource"
+	"github.com/savaki/eventsource/serializer/encryption"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	key1 = bytes.Repeat([]byte{1}, 32)
+	key2 = bytes.Repeat([]byte{2}, 32)
+)
+
+type Entity struct {
+	ID      string
+	Version int
+	SSN     string
+}
+
+type EntitySSNSet struct {
+	eventsource.Model
+	SSN string
+}
+
+func (e *Entity) On(event eventsource.Event) bool {
+	switch v := event.(type) {
+	case *EntitySSNSet:
+		e.ID = v.ID
+		e.Version = v.Version
+		e.SSN = v.SSN
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+func TestSerializer(t *testing.T) {
+	event := EntitySSNSet{
+		Model: eventsource.Model{ID: "123", Version: 1},
+		SSN:   "123-45-6789",
+	}
+
+	serializer := encryption.New(eventsource.JSONSerializer(), encryption.NewKeyring("key-1", key1))
+	serializer.Bind(EntitySSNSet{})
+
+	record, err := serializer.Serialize(event)
+	assert.Nil(t, err)
+	assert.Equal(t, "EntitySSNSet", record.Type)
+	assert.False(t, bytes.Contains(record.Data, []byte(event.SSN)))
+
+	keyID, ok := encryption.KeyID(record.Data)
+	assert.True(t, ok)
+	assert.Equal(t, "key-1", keyID)
+
+	v, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+	assert.Equal(t, &event, v)
+}
+
+func TestSerializerRotate(t *testing.T) {
+	event := EntitySSNSet{
+		Model: eventsource.Model{ID: "123", Version: 1},
+		SSN:   "123-45-6789",
+	}
+
+	keyring := encryption.NewKeyring("key-1", key1)
+	serializer := encryption.New(eventsource.JSONSerializer(), keyring)
+	serializer.Bind(EntitySSNSet{})
+
+	retired, err := serializer.Serialize(event)
+	assert.Nil(t, err)
+
+	keyring.Rotate("key-2", key2)
+
+	// Test - Records encrypted with the retired key can still be read
+
+	v, err := serializer.Deserialize(retired)
+	assert.Nil(t, err)
+	assert.Equal(t, &event, v)
+
+	// Test - Reencrypt moves the record to the current key
+
+	record, err := serializer.Reencrypt(retired)
+	assert.Nil(t, err)
+
+	keyID, _ := encryption.KeyID(record.Data)
+	assert.Equal(t, "key-2", keyID)
+
+	keyring.Remove("key-1")
+
+	v, err = serializer.Deserialize(record)
+	assert.Nil(t, err)
+	assert.Equal(t, &event, v)
+
+	_, err = serializer.Deserialize(retired)
+	assert.NotNil(t, err)
+}
+
+func TestSerializerErrors(t *testing.T) {
+	event := EntitySSNSet{
+		Model: eventsource.Model{ID: "123", Version: 1},
+		SSN:   "123-45-6789",
+	}
+
+	t.Run("invalid key", func(t *testing.T) {
+		serializer := encryption.New(eventsource.JSONSerializer(), encryption.NewKeyring("key-1", []byte("short")))
+		_, err := serializer.Serialize(event)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		serializer := encryption.New(eventsource.JSONSerializer(), encryption.NewKeyring("key-1", key1))
+		serializer.Bind(EntitySSNSet{})
+
+		record, err := serializer.Serialize(event)
+		assert.Nil(t, err)
+
+		record.Data[len(record.Data)-1] ^= 0xff
+		_, err = serializer.Deserialize(record)
+		assert.NotNil(t, err)
+
+		v, ok := err.(eventsource.Error)
+		assert.True(t, ok)
+		assert.Equal(t, eventsource.InvalidEncoding, v.Code())
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		record, err := encryption.New(eventsource.JSONSerializer(), encryption.NewKeyring("key-1", key1)).Serialize(event)
+		assert.Nil(t, err)
+
+		serializer := encryption.New(eventsource.JSONSerializer(), encryption.NewKeyring("key-2", key2))
+		_, err = serializer.Deserialize(record)
+		assert.NotNil(t, err)
+
+		v, ok := err.(eventsource.Error)
+		assert.True(t, ok)
+		assert.Equal(t, encryption.ErrKeyNotFound, v.Cause())
+	})
+}
+
+func TestSerializerPlaintext(t *testing.T) {
+	event := EntitySSNSet{
+		Model: eventsource.Model{ID: "123", Version: 1},
+		SSN:   "123-45-6789",
+	}
+
+	record, err := eventsource.JSONSerializer().Serialize(event)
+	assert.Nil(t, err)
+
+	serializer := encryption.New(eventsource.JSONSerializer(), encryption.NewKeyring("key-1", key1))
+	serializer.Bind(EntitySSNSet{})
+
+	v, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+	assert.Equal(t, &event, v)
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	serializer := encryption.New(eventsource.JSONSerializer(), encryption.NewKeyring("key-1", key1))
+	repository := eventsource.New(&Entity{}, eventsource.WithSerializer(serializer))
+	repository.Bind(EntitySSNSet{})
+
+	err := repository.Save(ctx, &EntitySSNSet{
+		Model: eventsource.Model{ID: id, Version: 1},
+		SSN:   "123-45-6789",
+	})
+	assert.Nil(t, err)
+
+	v, err := repository.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, &Entity{ID: id, Version: 1, SSN: "123-45-6789"}, v)
+}
@@ -0,0 +1,149 @@
+// Package msgpackserializer provides a MessagePack eventsource.Serializer for plain Go structs
+package msgpackserializer
+
+import (
+	"bytes"
+	"reflect"
+
+	"github.com/savaki/eventsource"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// structTag is consulted for field names when a field has no msgpack tag, so events tagged for the JSONSerializer
+// encode the same fields
+const structTag = "json"
+
+type envelope struct {
+	Type    string             `msgpack:"t"`
+	Version int                `msgpack:"v,omitempty"`
+	Data    msgpack.RawMessage `msgpack:"d"`
+}
+
+// Serializer implements eventsource.Serializer using MessagePack.  Like the JSONSerializer, events are encoded via
+// reflection and wrapped in an envelope holding the event type and schema version.
+type Serializer struct {
+	types    *eventsource.TypeRegistry
+	upcaster *eventsource.Upcaster
+}
+
+// Option provides optional configuration to the Serializer
+type Option func(*Serializer)
+
+// WithUpcaster upcasts stored events to the current schema of the bound event types prior to unmarshaling them
+func WithUpcaster(upcaster *eventsource.Upcaster) Option {
+	return func(s *Serializer) {
+		s.upcaster = upcaster
+	}
+}
+
+// WithTypeRegistry specifies the TypeRegistry events are bound to, allowing the registry, along with its aliases, to
+// be shared
+func WithTypeRegistry(types *eventsource.TypeRegistry) Option {
+	return func(s *Serializer) {
+		s.types = types
+	}
+}
+
+// New returns a new MessagePack Serializer
+func New(opts ...Option) *Serializer {
+	s := &Serializer{
+		types: eventsource.NewTypeRegistry(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Bind implements the eventsource.Serializer interface
+func (s *Serializer) Bind(events ...eventsource.Event) error {
+	return s.types.Register(events...)
+}
+
+// Serialize implements the eventsource.Serializer interface
+func (s *Serializer) Serialize(event eventsource.Event) (eventsource.Record, error) {
+	eventType, _ := eventsource.EventType(event)
+
+	data, err := marshal(event)
+	if err != nil {
+		return eventsource.Record{}, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to encode event")
+	}
+
+	wrapper := envelope{
+		Type: eventType,
+		Data: data,
+	}
+	if sv, ok := event.(eventsource.SchemaVersioner); ok {
+		wrapper.Version = sv.SchemaVersion()
+	}
+
+	data, err = marshal(wrapper)
+	if err != nil {
+		return eventsource.Record{}, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to encode event")
+	}
+
+	record := eventsource.Record{
+		Version: event.EventVersion(),
+		At:      eventsource.Time(event.EventAt()),
+		Type:    eventType,
+		Data:    data,
+	}
+	if m, ok := event.(eventsource.MetadataProvider); ok {
+		record.Metadata = m.EventMetadata()
+	}
+
+	return record, nil
+}
+
+// Deserialize implements the eventsource.Serializer interface
+func (s *Serializer) Deserialize(record eventsource.Record) (eventsource.Event, error) {
+	wrapper := envelope{}
+	if err := unmarshal(record.Data, &wrapper); err != nil {
+		return nil, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to unmarshal event")
+	}
+
+	if s.upcaster != nil {
+		eventType, version, data, err := s.upcaster.Upcast(wrapper.Type, wrapper.Version, wrapper.Data)
+		if err != nil {
+			return nil, err
+		}
+		wrapper.Type, wrapper.Version, wrapper.Data = eventType, version, data
+	}
+
+	t, ok := s.types.Lookup(wrapper.Type)
+	if !ok {
+		return nil, eventsource.NewError(nil, eventsource.UnboundEventType, "unbound event type, %v", wrapper.Type)
+	}
+
+	v := reflect.New(t).Interface()
+	if err := unmarshal(wrapper.Data, v); err != nil {
+		return nil, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to unmarshal event data into %#v", v)
+	}
+
+	if m, ok := v.(eventsource.MetadataReceiver); ok && record.Metadata != nil {
+		m.SetEventMetadata(record.Metadata)
+	}
+
+	return v.(eventsource.Event), nil
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	enc := msgpack.NewEncoder(buf)
+	enc.SetCustomStructTag(structTag)
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag(structTag)
+	return dec.Decode(v)
+}
@@ -0,0 +1,171 @@
+package msgpackserializer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/savaki/eventsource"
+	"github.com/savaki/eventsource/serializer/msgpackserializer"
+	"github.com/stretchr/testify/assert"
+)
+
+type User struct {
+	ID      string
+	Version int
+	Name    string
+	Email   string
+}
+
+type UserCreated struct {
+	eventsource.Model
+}
+
+type UserNameSet struct {
+	eventsource.ModelWithMetadata
+	Name string
+}
+
+// UserEmailSet defines an event via tags, with a type name that differs from the struct name
+type UserEmailSet struct {
+	ID      string    `json:"id"`
+	Version int       `json:"version"`
+	At      time.Time `json:"at"`
+	Email   string    `json:"email"`
+}
+
+func (u UserEmailSet) AggregateID() string { return u.ID }
+func (u UserEmailSet) EventVersion() int   { return u.Version }
+func (u UserEmailSet) EventAt() time.Time  { return u.At }
+func (u UserEmailSet) EventType() string   { return "EmailSet" }
+
+func (u *User) On(event eventsource.Event) bool {
+	switch v := event.(type) {
+	case *UserCreated:
+		u.ID = v.ID
+
+	case *UserNameSet:
+		u.Name = v.Name
+
+	case *UserEmailSet:
+		u.Email = v.Email
+
+	default:
+		return false
+	}
+
+	u.Version = event.EventVersion()
+
+	return true
+}
+
+func TestSerializer(t *testing.T) {
+	event := UserNameSet{
+		ModelWithMetadata: eventsource.ModelWithMetadata{
+			Model:    eventsource.Model{ID: "123", Version: 456, At: time.Unix(1, 0)},
+			Metadata: map[string]string{eventsource.MetadataCorrelationID: "abc"},
+		},
+		Name: "blah",
+	}
+
+	serializer := msgpackserializer.New()
+	serializer.Bind(event)
+	record, err := serializer.Serialize(event)
+	assert.Nil(t, err)
+	assert.Equal(t, "UserNameSet", record.Type)
+	assert.Equal(t, event.Metadata, record.Metadata)
+
+	v, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+
+	found, ok := v.(*UserNameSet)
+	assert.True(t, ok)
+	assert.Equal(t, event.ID, found.ID)
+	assert.Equal(t, event.Version, found.Version)
+	assert.True(t, event.At.Equal(found.At))
+	assert.Equal(t, event.Name, found.Name)
+	assert.Equal(t, event.Metadata, found.Metadata)
+}
+
+func TestSerializerEventTyper(t *testing.T) {
+	event := UserEmailSet{ID: "123", Version: 1, Email: "user@example.com"}
+
+	serializer := msgpackserializer.New()
+	serializer.Bind(event)
+	record, err := serializer.Serialize(event)
+	assert.Nil(t, err)
+	assert.Equal(t, "EmailSet", record.Type)
+
+	v, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+	assert.Equal(t, event.Email, v.(*UserEmailSet).Email)
+}
+
+func TestSerializerSize(t *testing.T) {
+	event := UserEmailSet{ID: "123", Version: 1, At: time.Now(), Email: "user@example.com"}
+
+	serializer := msgpackserializer.New()
+	record, err := serializer.Serialize(event)
+	assert.Nil(t, err)
+
+	record2, err := eventsource.JSONSerializer().Serialize(event)
+	assert.Nil(t, err)
+	assert.True(t, len(record.Data) < len(record2.Data), "expected msgpack to be smaller than json")
+}
+
+func TestSerializerUnbound(t *testing.T) {
+	serializer := msgpackserializer.New()
+	record, err := serializer.Serialize(UserCreated{Model: eventsource.Model{ID: "123"}})
+	assert.Nil(t, err)
+
+	_, err = serializer.Deserialize(record)
+	assert.NotNil(t, err)
+
+	v, ok := err.(eventsource.Error)
+	assert.True(t, ok)
+	assert.Equal(t, eventsource.UnboundEventType, v.Code())
+}
+
+func TestSerializerUpcaster(t *testing.T) {
+	upcaster := eventsource.NewUpcaster()
+	err := upcaster.Register("UserRenamed", 0, func(eventType string, data []byte) (string, []byte, error) {
+		return "UserNameSet", data, nil
+	})
+	assert.Nil(t, err)
+
+	record, err := msgpackserializer.New().Serialize(UserRenamed{UserNameSet{Name: "blah"}})
+	assert.Nil(t, err)
+
+	serializer := msgpackserializer.New(msgpackserializer.WithUpcaster(upcaster))
+	serializer.Bind(UserNameSet{})
+
+	v, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+	assert.Equal(t, "blah", v.(*UserNameSet).Name)
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	repository := eventsource.New(&User{}, eventsource.WithSerializer(msgpackserializer.New()))
+	repository.Bind(UserCreated{}, UserNameSet{}, UserEmailSet{})
+
+	err := repository.Save(ctx,
+		&UserCreated{Model: eventsource.Model{ID: id, Version: 1}},
+		&UserNameSet{ModelWithMetadata: eventsource.ModelWithMetadata{Model: eventsource.Model{ID: id, Version: 2}}, Name: "Jones"},
+		&UserEmailSet{ID: id, Version: 3, Email: "jones@example.com"},
+	)
+	assert.Nil(t, err)
+
+	v, err := repository.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, &User{ID: id, Version: 3, Name: "Jones", Email: "jones@example.com"}, v)
+}
+
+// UserRenamed was the former name of UserNameSet
+type UserRenamed struct {
+	UserNameSet
+}
+
+func (UserRenamed) EventType() string { return "UserRenamed" }
@@ -0,0 +1,99 @@
+package pii
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	// keySize is the size of the keys generated by MemoryKeyStore; selects AES-256
+	keySize = 32
+)
+
+var (
+	// ErrKeyNotFound is returned by a KeyStore when the subject has no key, either because none has been created or
+	// because the subject was forgotten
+	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrSubjectForgotten is returned by a KeyStore when a key is requested for a subject that has been forgotten
+	ErrSubjectForgotten = errors.New("subject forgotten")
+)
+
+// KeyStore holds the per-subject keys used to encrypt personal data.  Keys must be 16, 24, or 32 bytes long to select
+// AES-128, AES-192, or AES-256.  KeyStore should be held separately from the event Store, as forgetting a subject
+// relies on its key being unrecoverable.
+type KeyStore interface {
+	// Key returns the key of the subject; returns ErrKeyNotFound if the subject has no key
+	Key(subjectID string) ([]byte, error)
+
+	// CreateKey returns the key of the subject, generating one if the subject has no key; returns
+	// ErrSubjectForgotten if the subject has been forgotten
+	CreateKey(subjectID string) ([]byte, error)
+
+	// Forget destroys the key of the subject, rendering the personal data of the subject unreadable
+	Forget(subjectID string) error
+}
+
+// MemoryKeyStore provides an in-memory implementation of KeyStore
+type MemoryKeyStore struct {
+	mux       *sync.Mutex
+	keys      map[string][]byte
+	forgotten map[string]struct{}
+}
+
+// NewMemoryKeyStore returns a new, empty MemoryKeyStore
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{
+		mux:       &sync.Mutex{},
+		keys:      map[string][]byte{},
+		forgotten: map[string]struct{}{},
+	}
+}
+
+// Key implements the KeyStore interface
+func (m *MemoryKeyStore) Key(subjectID string) ([]byte, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	key, ok := m.keys[subjectID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// CreateKey implements the KeyStore interface
+func (m *MemoryKeyStore) CreateKey(subjectID string) ([]byte, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if _, ok := m.forgotten[subjectID]; ok {
+		return nil, ErrSubjectForgotten
+	}
+
+	if key, ok := m.keys[subjectID]; ok {
+		return key, nil
+	}
+
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	m.keys[subjectID] = key
+
+	return key, nil
+}
+
+// Forget implements the KeyStore interface
+func (m *MemoryKeyStore) Forget(subjectID string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.keys, subjectID)
+	m.forgotten[subjectID] = struct{}{}
+
+	return nil
+}
@@ -0,0 +1,288 @@
+// Package pii provides crypto-shredding of personal data held within events.
+//
+// String fields tagged with pii are encrypted with a key specific to the subject of the event, by default the
+// aggregate the event belongs to.  Forgetting the subject destroys its key; events are still loaded afterwards, but
+// their pii fields are redacted to the empty string.  Snapshots encode the aggregate rather than its events and are
+// not encrypted; the snapshots of a forgotten subject must be removed separately.
+//
+//	type UserEmailSet struct {
+//		eventsource.Model
+//		Email string `pii:"true"`
+//	}
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/savaki/eventsource"
+)
+
+const (
+	// tagName is the struct tag that marks a field as personal data
+	tagName = "pii"
+
+	// prefix begins each encrypted field value, distinguishing it from values saved prior to encryption
+	prefix = "pii:v1:"
+)
+
+var (
+	errTruncated = errors.New("encrypted field truncated")
+)
+
+// SubjectProvider is an optional interface an Event may implement to specify the subject whose key encrypts its pii
+// fields.  Events that do not implement SubjectProvider use their aggregate id as the subject.
+type SubjectProvider interface {
+	// PIISubject returns the id of the subject the personal data belongs to
+	PIISubject() string
+}
+
+// Serializer wraps an eventsource.Serializer, encrypting the pii fields of each event before passing the event on
+type Serializer struct {
+	serializer eventsource.Serializer
+	keys       KeyStore
+}
+
+// New returns a Serializer that encrypts pii fields with keys from keys and encodes events with serializer
+func New(serializer eventsource.Serializer, keys KeyStore) *Serializer {
+	return &Serializer{
+		serializer: serializer,
+		keys:       keys,
+	}
+}
+
+// Bind implements the eventsource.Serializer interface; returns an error if a pii field is not a string
+func (s *Serializer) Bind(events ...eventsource.Event) error {
+	for _, event := range events {
+		t := reflect.TypeOf(event)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if err := validate(t); err != nil {
+			return err
+		}
+	}
+
+	return s.serializer.Bind(events...)
+}
+
+// Serialize implements the eventsource.Serializer interface; the event itself is not modified
+func (s *Serializer) Serialize(event eventsource.Event) (eventsource.Record, error) {
+	v := reflect.Indirect(reflect.ValueOf(event))
+	if v.Kind() != reflect.Struct || !hasPII(v.Type()) {
+		return s.serializer.Serialize(event)
+	}
+
+	subjectID := subject(event)
+	key, err := s.keys.CreateKey(subjectID)
+	if err != nil {
+		return eventsource.Record{}, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to create key for subject, %v", subjectID)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return eventsource.Record{}, eventsource.NewError(err, eventsource.InvalidEncoding, "invalid key for subject, %v", subjectID)
+	}
+
+	encrypted := reflect.New(v.Type())
+	encrypted.Elem().Set(v)
+
+	err = walk(encrypted.Elem(), func(field reflect.Value) error {
+		if field.String() == "" {
+			return nil
+		}
+
+		value, err := encrypt(aead, subjectID, field.String())
+		if err != nil {
+			return err
+		}
+		field.SetString(value)
+		return nil
+	})
+	if err != nil {
+		return eventsource.Record{}, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to encrypt event")
+	}
+
+	return s.serializer.Serialize(encrypted.Interface().(eventsource.Event))
+}
+
+// Deserialize implements the eventsource.Serializer interface.  If the subject of the event has been forgotten, its
+// pii fields are set to the empty string.
+func (s *Serializer) Deserialize(record eventsource.Record) (eventsource.Event, error) {
+	event, err := s.serializer.Deserialize(record)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.ValueOf(event)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct || !hasPII(v.Elem().Type()) {
+		return event, nil
+	}
+
+	subjectID := subject(event)
+
+	var aead cipher.AEAD
+	redact := false
+	err = walk(v.Elem(), func(field reflect.Value) error {
+		value := field.String()
+		if !strings.HasPrefix(value, prefix) {
+			return nil
+		}
+
+		if redact {
+			field.SetString("")
+			return nil
+		}
+
+		if aead == nil {
+			key, err := s.keys.Key(subjectID)
+			if errors.Is(err, ErrKeyNotFound) {
+				redact = true
+				field.SetString("")
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			aead, err = newAEAD(key)
+			if err != nil {
+				return err
+			}
+		}
+
+		plaintext, err := decrypt(aead, subjectID, value)
+		if err != nil {
+			return err
+		}
+		field.SetString(plaintext)
+		return nil
+	})
+	if err != nil {
+		return nil, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to decrypt event for subject, %v", subjectID)
+	}
+
+	return event, nil
+}
+
+// Forget destroys the key of the subject; see KeyStore
+func (s *Serializer) Forget(subjectID string) error {
+	return s.keys.Forget(subjectID)
+}
+
+func subject(event eventsource.Event) string {
+	if v, ok := event.(SubjectProvider); ok {
+		return v.PIISubject()
+	}
+	return event.AggregateID()
+}
+
+// validate ensures every pii field of t is a string
+func validate(t reflect.Type) error {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, ok := field.Tag.Lookup(tagName); ok {
+			if field.Type.Kind() != reflect.String {
+				return eventsource.NewError(nil, eventsource.InvalidEncoding, "pii field, %v.%v, must be a string", t.Name(), field.Name)
+			}
+			continue
+		}
+
+		if err := validate(field.Type); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hasPII reports whether t, or any struct field of t, contains a pii field
+func hasPII(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, ok := field.Tag.Lookup(tagName); ok {
+			return true
+		}
+		if field.Type.Kind() == reflect.Struct && hasPII(field.Type) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// walk calls fn with each settable pii string field of v, descending into struct fields
+func walk(v reflect.Value, fn func(field reflect.Value) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+
+		if _, ok := t.Field(i).Tag.Lookup(tagName); ok {
+			if field.Kind() != reflect.String {
+				continue
+			}
+			if err := fn(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if field.Kind() == reflect.Struct {
+			if err := walk(field, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func encrypt(aead cipher.AEAD, subjectID, value string) (string, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(subjectID))
+	return prefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func decrypt(aead cipher.AEAD, subjectID, value string) (string, error) {
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errTruncated
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(subjectID))
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
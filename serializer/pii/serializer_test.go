@@ -0,0 +1,169 @@
+package pii_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/savaki/eventsource"
+	"github.com/savaki/eventsource/serializer/pii"
+	"github.com/stretchr/testify/assert"
+)
+
+type User struct {
+	ID      string
+	Version int
+	Name    string
+	Email   string
+	Plan    string
+}
+
+type UserCreated struct {
+	eventsource.Model
+	Name  string `pii:"true"`
+	Email string `pii:"true"`
+	Plan  string
+}
+
+type UserEmailSet struct {
+	eventsource.Model
+	Email string `pii:"true"`
+}
+
+func (u *User) On(event eventsource.Event) bool {
+	switch v := event.(type) {
+	case *UserCreated:
+		u.ID = v.ID
+		u.Name = v.Name
+		u.Email = v.Email
+		u.Plan = v.Plan
+
+	case *UserEmailSet:
+		u.Email = v.Email
+
+	default:
+		return false
+	}
+
+	u.Version = event.EventVersion()
+
+	return true
+}
+
+func TestSerializer(t *testing.T) {
+	event := UserCreated{
+		Model: eventsource.Model{ID: "123", Version: 1},
+		Name:  "Jones",
+		Email: "jones@example.com",
+		Plan:  "gold",
+	}
+
+	serializer := pii.New(eventsource.JSONSerializer(), pii.NewMemoryKeyStore())
+	err := serializer.Bind(event)
+	assert.Nil(t, err)
+
+	record, err := serializer.Serialize(event)
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(record.Data, []byte(event.Name)))
+	assert.False(t, bytes.Contains(record.Data, []byte(event.Email)))
+	assert.True(t, bytes.Contains(record.Data, []byte(event.Plan)))
+	assert.Equal(t, "Jones", event.Name, "expected event to be unmodified")
+
+	v, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+	assert.Equal(t, &event, v)
+}
+
+func TestSerializerForget(t *testing.T) {
+	serializer := pii.New(eventsource.JSONSerializer(), pii.NewMemoryKeyStore())
+	serializer.Bind(UserCreated{})
+
+	record, err := serializer.Serialize(UserCreated{
+		Model: eventsource.Model{ID: "123", Version: 1},
+		Name:  "Jones",
+		Plan:  "gold",
+	})
+	assert.Nil(t, err)
+
+	other, err := serializer.Serialize(UserCreated{
+		Model: eventsource.Model{ID: "abc", Version: 1},
+		Name:  "Sarah",
+	})
+	assert.Nil(t, err)
+
+	err = serializer.Forget("123")
+	assert.Nil(t, err)
+
+	v, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+	assert.Equal(t, "", v.(*UserCreated).Name)
+	assert.Equal(t, "gold", v.(*UserCreated).Plan)
+
+	// Test - Other subjects are unaffected
+
+	v, err = serializer.Deserialize(other)
+	assert.Nil(t, err)
+	assert.Equal(t, "Sarah", v.(*UserCreated).Name)
+
+	// Test - Personal data is not collected for a forgotten subject
+
+	_, err = serializer.Serialize(UserEmailSet{
+		Model: eventsource.Model{ID: "123", Version: 2},
+		Email: "jones@example.com",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestSerializerBind(t *testing.T) {
+	type Invalid struct {
+		eventsource.Model
+		Age int `pii:"true"`
+	}
+
+	serializer := pii.New(eventsource.JSONSerializer(), pii.NewMemoryKeyStore())
+	err := serializer.Bind(Invalid{})
+	assert.NotNil(t, err)
+}
+
+func TestSerializerPlaintext(t *testing.T) {
+	event := UserCreated{
+		Model: eventsource.Model{ID: "123", Version: 1},
+		Name:  "Jones",
+	}
+
+	record, err := eventsource.JSONSerializer().Serialize(event)
+	assert.Nil(t, err)
+
+	serializer := pii.New(eventsource.JSONSerializer(), pii.NewMemoryKeyStore())
+	serializer.Bind(UserCreated{})
+
+	v, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+	assert.Equal(t, &event, v)
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	serializer := pii.New(eventsource.JSONSerializer(), pii.NewMemoryKeyStore())
+	repository := eventsource.New(&User{}, eventsource.WithSerializer(serializer))
+	repository.Bind(UserCreated{}, UserEmailSet{})
+
+	err := repository.Save(ctx,
+		&UserCreated{Model: eventsource.Model{ID: id, Version: 1}, Name: "Jones", Plan: "gold"},
+		&UserEmailSet{Model: eventsource.Model{ID: id, Version: 2}, Email: "jones@example.com"},
+	)
+	assert.Nil(t, err)
+
+	v, err := repository.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, &User{ID: id, Version: 2, Name: "Jones", Email: "jones@example.com", Plan: "gold"}, v)
+
+	err = serializer.Forget(id)
+	assert.Nil(t, err)
+
+	v, err = repository.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, &User{ID: id, Version: 2, Plan: "gold"}, v)
+}
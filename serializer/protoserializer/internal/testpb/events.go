@@ -0,0 +1,23 @@
+package testpb
+
+import "time"
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative events.proto
+
+// AggregateID implements part of the eventsource.Event interface
+func (x *UserCreated) AggregateID() string { return x.GetId() }
+
+// EventVersion implements part of the eventsource.Event interface
+func (x *UserCreated) EventVersion() int { return int(x.GetVersion()) }
+
+// EventAt implements part of the eventsource.Event interface
+func (x *UserCreated) EventAt() time.Time { return time.Unix(0, x.GetAt()*int64(time.Millisecond)) }
+
+// AggregateID implements part of the eventsource.Event interface
+func (x *UserNameSet) AggregateID() string { return x.GetId() }
+
+// EventVersion implements part of the eventsource.Event interface
+func (x *UserNameSet) EventVersion() int { return int(x.GetVersion()) }
+
+// EventAt implements part of the eventsource.Event interface
+func (x *UserNameSet) EventAt() time.Time { return time.Unix(0, x.GetAt()*int64(time.Millisecond)) }
@@ -0,0 +1,171 @@
+// Package protoserializer provides an eventsource.Serializer for events that are protocol buffer messages
+package protoserializer
+
+import (
+	"reflect"
+
+	"github.com/savaki/eventsource"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// envelope field numbers; the envelope is itself a valid protocol buffer message
+const (
+	fieldType    protowire.Number = 1
+	fieldData    protowire.Number = 2
+	fieldVersion protowire.Number = 3
+)
+
+// Serializer implements eventsource.Serializer for events that are also proto.Message values.  Each event is
+// written within a compact envelope holding the event type, the schema version, and the marshaled event.
+type Serializer struct {
+	types    *eventsource.TypeRegistry
+	upcaster *eventsource.Upcaster
+}
+
+// Option provides optional configuration to the Serializer
+type Option func(*Serializer)
+
+// WithUpcaster upcasts stored events to the current schema of the bound event types prior to unmarshaling them
+func WithUpcaster(upcaster *eventsource.Upcaster) Option {
+	return func(s *Serializer) {
+		s.upcaster = upcaster
+	}
+}
+
+// WithTypeRegistry specifies the TypeRegistry events are bound to, allowing the registry, along with its aliases, to
+// be shared
+func WithTypeRegistry(types *eventsource.TypeRegistry) Option {
+	return func(s *Serializer) {
+		s.types = types
+	}
+}
+
+// New returns a new protocol buffer Serializer
+func New(opts ...Option) *Serializer {
+	s := &Serializer{
+		types: eventsource.NewTypeRegistry(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Bind implements the eventsource.Serializer interface; each event must also implement proto.Message
+func (s *Serializer) Bind(events ...eventsource.Event) error {
+	for _, event := range events {
+		if _, ok := event.(proto.Message); !ok {
+			return eventsource.NewError(nil, eventsource.InvalidEncoding, "event, %T, does not implement proto.Message", event)
+		}
+	}
+
+	return s.types.Register(events...)
+}
+
+// Serialize implements the eventsource.Serializer interface
+func (s *Serializer) Serialize(event eventsource.Event) (eventsource.Record, error) {
+	message, ok := event.(proto.Message)
+	if !ok {
+		return eventsource.Record{}, eventsource.NewError(nil, eventsource.InvalidEncoding, "event, %T, does not implement proto.Message", event)
+	}
+
+	data, err := proto.Marshal(message)
+	if err != nil {
+		return eventsource.Record{}, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to encode event")
+	}
+
+	eventType, _ := eventsource.EventType(event)
+
+	version := 0
+	if sv, ok := event.(eventsource.SchemaVersioner); ok {
+		version = sv.SchemaVersion()
+	}
+
+	record := eventsource.Record{
+		Version: event.EventVersion(),
+		At:      eventsource.Time(event.EventAt()),
+		Type:    eventType,
+		Data:    marshalEnvelope(eventType, version, data),
+	}
+	if m, ok := event.(eventsource.MetadataProvider); ok {
+		record.Metadata = m.EventMetadata()
+	}
+
+	return record, nil
+}
+
+// Deserialize implements the eventsource.Serializer interface
+func (s *Serializer) Deserialize(record eventsource.Record) (eventsource.Event, error) {
+	eventType, version, data, err := unmarshalEnvelope(record.Data)
+	if err != nil {
+		return nil, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to unmarshal event")
+	}
+
+	if s.upcaster != nil {
+		eventType, version, data, err = s.upcaster.Upcast(eventType, version, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	t, ok := s.types.Lookup(eventType)
+	if !ok {
+		return nil, eventsource.NewError(nil, eventsource.UnboundEventType, "unbound event type, %v", eventType)
+	}
+
+	v := reflect.New(t).Interface()
+	if err := proto.Unmarshal(data, v.(proto.Message)); err != nil {
+		return nil, eventsource.NewError(err, eventsource.InvalidEncoding, "unable to unmarshal event data into %T", v)
+	}
+
+	if m, ok := v.(eventsource.MetadataReceiver); ok && record.Metadata != nil {
+		m.SetEventMetadata(record.Metadata)
+	}
+
+	return v.(eventsource.Event), nil
+}
+
+func marshalEnvelope(eventType string, version int, data []byte) []byte {
+	b := make([]byte, 0, len(eventType)+len(data)+16)
+	b = protowire.AppendTag(b, fieldType, protowire.BytesType)
+	b = protowire.AppendString(b, eventType)
+	b = protowire.AppendTag(b, fieldData, protowire.BytesType)
+	b = protowire.AppendBytes(b, data)
+	if version != 0 {
+		b = protowire.AppendTag(b, fieldVersion, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(version))
+	}
+	return b
+}
+
+func unmarshalEnvelope(b []byte) (eventType string, version int, data []byte, err error) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return "", 0, nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch {
+		case num == fieldType && typ == protowire.BytesType:
+			eventType, n = protowire.ConsumeString(b)
+		case num == fieldData && typ == protowire.BytesType:
+			data, n = protowire.ConsumeBytes(b)
+		case num == fieldVersion && typ == protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			version = int(v)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return "", 0, nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+
+	return eventType, version, data, nil
+}
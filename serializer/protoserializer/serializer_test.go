@@ -0,0 +1,156 @@
+package protoserializer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/savaki/eventsource"
+	"github.com/savaki/eventsource/serializer/protoserializer"
+	"github.com/savaki/eventsource/serializer/protoserializer/internal/testpb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+type User struct {
+	ID        string
+	Version   int
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (u *User) On(event eventsource.Event) bool {
+	switch v := event.(type) {
+	case *testpb.UserCreated:
+		u.ID = v.Id
+		u.CreatedAt = v.EventAt()
+
+	case *testpb.UserNameSet:
+		u.Name = v.Name
+
+	default:
+		return false
+	}
+
+	u.Version = event.EventVersion()
+	u.UpdatedAt = event.EventAt()
+
+	return true
+}
+
+func TestSerializer(t *testing.T) {
+	event := &testpb.UserNameSet{Id: "123", Version: 2, At: 1500, Name: "blah"}
+
+	serializer := protoserializer.New()
+	err := serializer.Bind(event)
+	assert.Nil(t, err)
+
+	record, err := serializer.Serialize(event)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, record.Version)
+	assert.Equal(t, eventsource.EpochMillis(1500), record.At)
+	assert.Equal(t, "UserNameSet", record.Type)
+
+	v, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+
+	found, ok := v.(*testpb.UserNameSet)
+	assert.True(t, ok)
+	assert.True(t, proto.Equal(event, found))
+}
+
+func TestSerializerErrors(t *testing.T) {
+	serializer := protoserializer.New()
+
+	t.Run("bind requires proto.Message", func(t *testing.T) {
+		err := serializer.Bind(eventsource.Model{ID: "123"})
+		assert.NotNil(t, err)
+
+		v, ok := err.(eventsource.Error)
+		assert.True(t, ok)
+		assert.Equal(t, eventsource.InvalidEncoding, v.Code())
+	})
+
+	t.Run("unbound event type", func(t *testing.T) {
+		record, err := serializer.Serialize(&testpb.UserCreated{Id: "123"})
+		assert.Nil(t, err)
+
+		_, err = serializer.Deserialize(record)
+		assert.NotNil(t, err)
+
+		v, ok := err.(eventsource.Error)
+		assert.True(t, ok)
+		assert.Equal(t, eventsource.UnboundEventType, v.Code())
+	})
+
+	t.Run("invalid encoding", func(t *testing.T) {
+		_, err := serializer.Deserialize(eventsource.Record{Data: []byte{0xff}})
+		assert.NotNil(t, err)
+
+		v, ok := err.(eventsource.Error)
+		assert.True(t, ok)
+		assert.Equal(t, eventsource.InvalidEncoding, v.Code())
+	})
+}
+
+func TestSerializerUpcaster(t *testing.T) {
+	upcaster := eventsource.NewUpcaster()
+	err := upcaster.Register("UserRenamed", 0, func(eventType string, data []byte) (string, []byte, error) {
+		return "UserNameSet", data, nil
+	})
+	assert.Nil(t, err)
+
+	// UserRenamed was the former name of UserNameSet; the message itself is unchanged
+	old := protoserializer.New()
+	old.Bind(&renamed{})
+	record, err := old.Serialize(&renamed{UserNameSet: &testpb.UserNameSet{Id: "123", Name: "blah"}})
+	assert.Nil(t, err)
+
+	serializer := protoserializer.New(protoserializer.WithUpcaster(upcaster))
+	serializer.Bind(&testpb.UserNameSet{})
+
+	v, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+	assert.Equal(t, "blah", v.(*testpb.UserNameSet).Name)
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	repository := eventsource.New(&User{}, eventsource.WithSerializer(protoserializer.New()))
+	err := repository.Bind(&testpb.UserCreated{}, &testpb.UserNameSet{})
+	assert.Nil(t, err)
+
+	err = repository.Save(ctx,
+		&testpb.UserCreated{Id: id, Version: 1, At: 3000},
+		&testpb.UserNameSet{Id: id, Version: 2, At: 4000, Name: "Jones"},
+	)
+	assert.Nil(t, err)
+
+	v, err := repository.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, &User{
+		ID:        id,
+		Version:   2,
+		Name:      "Jones",
+		CreatedAt: time.Unix(3, 0),
+		UpdatedAt: time.Unix(4, 0),
+	}, v)
+
+	err = repository.Save(ctx, &testpb.UserNameSet{Id: id, Version: 3, At: 5000, Name: "Sarah"})
+	assert.Nil(t, err)
+
+	v, err = repository.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Sarah", v.(*User).Name)
+	assert.Equal(t, 3, v.(*User).Version)
+}
+
+// renamed reports itself under the former event type of UserNameSet
+type renamed struct {
+	*testpb.UserNameSet
+}
+
+func (renamed) EventType() string { return "UserRenamed" }
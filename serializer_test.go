@@ -12,6 +12,11 @@ type EntitySetName struct {
 	Name string
 }
 
+type EntityAnnotated struct {
+	eventsource.ModelWithMetadata
+	Note string
+}
+
 func TestJSONSerializer(t *testing.T) {
 	event := EntitySetName{
 		Model: eventsource.Model{
@@ -26,6 +31,7 @@ func TestJSONSerializer(t *testing.T) {
 	record, err := serializer.Serialize(event)
 	assert.Nil(t, err)
 	assert.NotNil(t, record)
+	assert.Equal(t, "EntitySetName", record.Type)
 
 	v, err := serializer.Deserialize(record)
 	assert.Nil(t, err)
@@ -34,3 +40,23 @@ func TestJSONSerializer(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, &event, found)
 }
+
+func TestJSONSerializerMetadata(t *testing.T) {
+	event := EntityAnnotated{
+		ModelWithMetadata: eventsource.ModelWithMetadata{
+			Model:    eventsource.Model{ID: "123", Version: 456},
+			Metadata: map[string]string{eventsource.MetadataCorrelationID: "abc"},
+		},
+		Note: "blah",
+	}
+
+	serializer := eventsource.JSONSerializer()
+	serializer.Bind(event)
+	record, err := serializer.Serialize(event)
+	assert.Nil(t, err)
+	assert.Equal(t, event.Metadata, record.Metadata)
+
+	v, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+	assert.Equal(t, &event, v)
+}
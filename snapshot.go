@@ -0,0 +1,96 @@
+package eventsource
+
+import (
+	"context"
+	"sync"
+)
+
+// Snapshot holds the encoded state of an aggregate as of a specific version
+type Snapshot struct {
+	// AggregateID is the id of the aggregate the snapshot was taken of
+	AggregateID string
+
+	// Version is the version of the last event applied to the aggregate prior to the snapshot
+	Version int
+
+	// At indicates when the snapshot was taken
+	At EpochMillis
+
+	// Data contains the encoded aggregate
+	Data []byte
+}
+
+// SnapshotStore provides storage for snapshots
+type SnapshotStore interface {
+	// Save saves the snapshot to the store
+	Save(ctx context.Context, snapshot Snapshot) error
+
+	// Fetch retrieves the most recent snapshot of the aggregate whose version is no greater than version; 0 to fetch
+	// the most recent snapshot.  Returns an Error with the SnapshotNotFound code if no such snapshot exists
+	Fetch(ctx context.Context, aggregateID string, version int) (Snapshot, error)
+}
+
+// Snapshotter is the interface an Aggregate must implement to be used with WithSnapshots or WithCache; it controls
+// how the aggregate, including any unexported state, is encoded into a Snapshot or Cache.
+type Snapshotter interface {
+	// MarshalSnapshot encodes the aggregate
+	MarshalSnapshot() ([]byte, error)
+
+	// UnmarshalSnapshot restores the aggregate from data previously returned by MarshalSnapshot
+	UnmarshalSnapshot(data []byte) error
+}
+
+// MemorySnapshotStore provides an in-memory implementation of SnapshotStore
+type MemorySnapshotStore struct {
+	mux       *sync.Mutex
+	snapshots map[string][]Snapshot
+}
+
+// NewMemorySnapshotStore returns a new, empty MemorySnapshotStore
+func NewMemorySnapshotStore() *MemorySnapshotStore {
+	return &MemorySnapshotStore{
+		mux:       &sync.Mutex{},
+		snapshots: map[string][]Snapshot{},
+	}
+}
+
+// Save implements the SnapshotStore interface
+func (m *MemorySnapshotStore) Save(ctx context.Context, snapshot Snapshot) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	snapshots := m.snapshots[snapshot.AggregateID]
+	for index, s := range snapshots {
+		if s.Version == snapshot.Version {
+			snapshots[index] = snapshot
+			return nil
+		}
+	}
+
+	m.snapshots[snapshot.AggregateID] = append(snapshots, snapshot)
+	return nil
+}
+
+// Fetch implements the SnapshotStore interface
+func (m *MemorySnapshotStore) Fetch(ctx context.Context, aggregateID string, version int) (Snapshot, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	found := false
+	snapshot := Snapshot{}
+	for _, s := range m.snapshots[aggregateID] {
+		if version > 0 && s.Version > version {
+			continue
+		}
+		if !found || s.Version > snapshot.Version {
+			snapshot = s
+			found = true
+		}
+	}
+
+	if !found {
+		return Snapshot{}, NewError(nil, SnapshotNotFound, "no snapshot found for aggregate id, %v", aggregateID)
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,144 @@
+package eventsource_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/savaki/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+// SnapshotEntity is an Entity that implements Snapshotter and so may be snapshotted or cached
+type SnapshotEntity struct {
+	Entity
+}
+
+func (s *SnapshotEntity) MarshalSnapshot() ([]byte, error) {
+	return json.Marshal(s.Entity)
+}
+
+func (s *SnapshotEntity) UnmarshalSnapshot(data []byte) error {
+	return json.Unmarshal(data, &s.Entity)
+}
+
+func TestMemorySnapshotStore(t *testing.T) {
+	ctx := context.Background()
+	store := eventsource.NewMemorySnapshotStore()
+
+	_, err := store.Fetch(ctx, "abc", 0)
+	assert.NotNil(t, err)
+	v, ok := err.(eventsource.Error)
+	assert.True(t, ok)
+	assert.Equal(t, eventsource.SnapshotNotFound, v.Code())
+
+	s1 := eventsource.Snapshot{AggregateID: "abc", Version: 1, Data: []byte("a")}
+	s2 := eventsource.Snapshot{AggregateID: "abc", Version: 3, Data: []byte("b")}
+	assert.Nil(t, store.Save(ctx, s1))
+	assert.Nil(t, store.Save(ctx, s2))
+
+	found, err := store.Fetch(ctx, "abc", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, s2, found)
+
+	found, err = store.Fetch(ctx, "abc", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, s1, found)
+}
+
+func TestWithSnapshots(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	snapshots := eventsource.NewMemorySnapshotStore()
+	registry := eventsource.New(&SnapshotEntity{}, eventsource.WithSnapshots(snapshots, 2))
+	registry.Bind(EntityCreated{}, EntityNameSet{})
+
+	err := registry.Save(ctx,
+		&EntityCreated{
+			Model: eventsource.Model{ID: id, Version: 1},
+		},
+		&EntityNameSet{
+			Model: eventsource.Model{ID: id, Version: 2},
+			Name:  "Jones",
+		},
+	)
+	assert.Nil(t, err)
+
+	// Test - Loading replays 2 events and so should save a snapshot
+
+	v, err := registry.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Jones", v.(*SnapshotEntity).Name)
+
+	snapshot, err := snapshots.Fetch(ctx, id, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, snapshot.Version)
+
+	// Test - Loading restores the snapshot and applies the remaining events
+
+	err = registry.Save(ctx, &EntityNameSet{
+		Model: eventsource.Model{ID: id, Version: 3},
+		Name:  "Sarah",
+	})
+	assert.Nil(t, err)
+
+	v, err = registry.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, id, v.(*SnapshotEntity).ID)
+	assert.Equal(t, "Sarah", v.(*SnapshotEntity).Name)
+	assert.Equal(t, 3, v.(*SnapshotEntity).Version)
+
+	snapshot, err = snapshots.Fetch(ctx, id, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, snapshot.Version, "expected no snapshot after replaying a single event")
+}
+
+func TestLoadVersionWithSnapshots(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	snapshots := eventsource.NewMemorySnapshotStore()
+	registry := eventsource.New(&SnapshotEntity{}, eventsource.WithSnapshots(snapshots, 2))
+	registry.Bind(EntityCreated{}, EntityNameSet{})
+
+	err := registry.Save(ctx,
+		&EntityCreated{Model: eventsource.Model{ID: id, Version: 1}},
+		&EntityNameSet{Model: eventsource.Model{ID: id, Version: 2}, Name: "Jones"},
+		&EntityNameSet{Model: eventsource.Model{ID: id, Version: 3}, Name: "Sarah"},
+		&EntityNameSet{Model: eventsource.Model{ID: id, Version: 4}, Name: "Jane"},
+	)
+	assert.Nil(t, err)
+
+	err = snapshots.Save(ctx, eventsource.Snapshot{AggregateID: id, Version: 2, Data: []byte(`{"ID":"123","Version":2,"Name":"Jones"}`)})
+	assert.Nil(t, err)
+
+	// Test - The snapshot is applied along with the events up to the version requested
+
+	v, err := registry.LoadVersion(ctx, id, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, "Sarah", v.(*SnapshotEntity).Name)
+	assert.Equal(t, 3, v.(*SnapshotEntity).Version)
+
+	// Test - Snapshots newer than the version requested are ignored
+
+	err = snapshots.Save(ctx, eventsource.Snapshot{AggregateID: id, Version: 4, Data: []byte(`{"ID":"123","Version":4,"Name":"Jane"}`)})
+	assert.Nil(t, err)
+
+	v, err = registry.LoadVersion(ctx, id, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "", v.(*SnapshotEntity).Name)
+	assert.Equal(t, 1, v.(*SnapshotEntity).Version)
+
+	// Test - Loading a prior version saves no snapshot
+
+	snapshot, err := snapshots.Fetch(ctx, id, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, snapshot.Version)
+}
+
+func TestWithSnapshotsRequiresSnapshotter(t *testing.T) {
+	snapshots := eventsource.NewMemorySnapshotStore()
+	assert.Panics(t, func() { eventsource.New(&Entity{}, eventsource.WithSnapshots(snapshots, 2)) })
+	assert.NotPanics(t, func() { eventsource.New(&SnapshotEntity{}, eventsource.WithSnapshots(snapshots, 2)) })
+}
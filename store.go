@@ -17,8 +17,27 @@ type Record struct {
 	// At indicates when the event happened; provided as a utility for the store
 	At EpochMillis
 
+	// Type is the event type of the Data, as provided by the Serializer; allows stores to filter events without
+	// deserializing them
+	Type string
+
 	// Data contains the Serializer encoded version of the data
 	Data []byte
+
+	// Metadata contains optional information about the event e.g. correlation id, causation id, actor
+	Metadata map[string]string
+}
+
+// StreamRecord provides a Record along with its location in the global log of events
+type StreamRecord struct {
+	Record
+
+	// AggregateID is the id of the aggregate the Record belongs to
+	AggregateID string
+
+	// Position is the position of the Record within the global log of events; positions are increasing, but not
+	// necessarily contiguous
+	Position int64
 }
 
 // Store provides storage for events
@@ -30,36 +49,308 @@ type Store interface {
 	Fetch(ctx context.Context, aggregateID string, version int) (History, error)
 }
 
-// memoryStore provides an in-memory implementation of Store
-type memoryStore struct {
+// VersionSaver is an optional interface that a Store may implement to provide optimistic concurrency control
+type VersionSaver interface {
+	// SaveVersion saves events to the store provided no record newer than expectedVersion has been saved for the
+	// aggregate; otherwise an Error with the DuplicateVersion code is returned and no records are saved
+	SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...Record) error
+}
+
+// AfterFetcher is an optional interface that a Store may implement to fetch only the records following a known version
+type AfterFetcher interface {
+	// FetchAfter retrieves the History of events with the specified aggregate id and a version greater than version
+	FetchAfter(ctx context.Context, aggregateID string, version int) (History, error)
+}
+
+// Reader is an optional interface that a Store may implement to provide an ordered log of events across all aggregates
+type Reader interface {
+	// Read returns up to recordCount records with a position greater than startingPosition, in position order.  Use
+	// a startingPosition of 0 to read from the beginning of the log
+	Read(ctx context.Context, startingPosition int64, recordCount int) ([]StreamRecord, error)
+}
+
+// TypeReader is an optional interface that a Store may implement to read the log of events filtered by event type
+type TypeReader interface {
+	// ReadTypes returns up to recordCount records with a position greater than startingPosition and one of the event
+	// types provided, in position order
+	ReadTypes(ctx context.Context, startingPosition int64, recordCount int, eventTypes ...string) ([]StreamRecord, error)
+}
+
+// Filter selects the records delivered by a Subscriber; returns true if the record should be delivered
+type Filter func(record StreamRecord) bool
+
+// FilterAggregateID returns a Filter that accepts records belonging to any of the aggregate ids provided
+func FilterAggregateID(aggregateIDs ...string) Filter {
+	ids := make(map[string]struct{}, len(aggregateIDs))
+	for _, id := range aggregateIDs {
+		ids[id] = struct{}{}
+	}
+
+	return func(record StreamRecord) bool {
+		_, ok := ids[record.AggregateID]
+		return ok
+	}
+}
+
+// FilterEventType returns a Filter that accepts records of any of the event types provided
+func FilterEventType(eventTypes ...string) Filter {
+	types := make(map[string]struct{}, len(eventTypes))
+	for _, eventType := range eventTypes {
+		types[eventType] = struct{}{}
+	}
+
+	return func(record StreamRecord) bool {
+		_, ok := types[record.Type]
+		return ok
+	}
+}
+
+// Match returns true if the record is accepted by every filter provided
+func Match(record StreamRecord, filters ...Filter) bool {
+	for _, filter := range filters {
+		if !filter(record) {
+			return false
+		}
+	}
+	return true
+}
+
+// Subscriber is an optional interface that a Store may implement to notify consumers of newly saved records
+type Subscriber interface {
+	// Subscribe returns a channel that receives the records saved after the call to Subscribe that are accepted by
+	// all of the filters.  The channel is closed once ctx is done.
+	Subscribe(ctx context.Context, filters ...Filter) <-chan StreamRecord
+}
+
+// MemoryStore provides a concurrency safe, in-memory implementation of Store suitable for tests and prototypes.  In
+// addition to Store, MemoryStore implements VersionSaver, AfterFetcher, Reader, TypeReader, and Subscriber.
+type MemoryStore struct {
 	mux        *sync.Mutex
 	eventsByID map[string]History
+	log        []StreamRecord
+
+	// changed is closed, and replaced, whenever records are appended to the log
+	changed chan struct{}
 }
 
-func newMemoryStore() *memoryStore {
-	return &memoryStore{
+// NewMemoryStore returns a new, empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
 		mux:        &sync.Mutex{},
 		eventsByID: map[string]History{},
+		changed:    make(chan struct{}),
+	}
+}
+
+// Save implements the Store interface; returns an Error with the DuplicateVersion code if any of the record
+// versions have already been saved
+func (m *MemoryStore) Save(ctx context.Context, aggregateID string, records ...Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.append(aggregateID, records...)
+}
+
+// SaveVersion implements the VersionSaver interface
+func (m *MemoryStore) SaveVersion(ctx context.Context, aggregateID string, expectedVersion int, records ...Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if history := m.eventsByID[aggregateID]; len(history) > 0 {
+		if version := history[len(history)-1].Version; version > expectedVersion {
+			return NewError(nil, DuplicateVersion, "aggregate, %v, at version %v; expected version %v", aggregateID, version, expectedVersion)
+		}
 	}
+
+	return m.append(aggregateID, records...)
 }
 
-func (m *memoryStore) Save(ctx context.Context, aggregateID string, records ...Record) error {
-	if _, ok := m.eventsByID[aggregateID]; !ok {
-		m.eventsByID[aggregateID] = History{}
+// append adds the records to the aggregate's history; callers must hold the lock
+func (m *MemoryStore) append(aggregateID string, records ...Record) error {
+	history := m.eventsByID[aggregateID]
+
+	versions := make(map[int]struct{}, len(history)+len(records))
+	for _, record := range history {
+		versions[record.Version] = struct{}{}
+	}
+	for _, record := range records {
+		if _, ok := versions[record.Version]; ok {
+			return NewError(nil, DuplicateVersion, "aggregate, %v, already contains version %v", aggregateID, record.Version)
+		}
+		versions[record.Version] = struct{}{}
 	}
 
-	history := append(m.eventsByID[aggregateID], records...)
+	history = append(history, records...)
 	sort.Slice(history, func(i, j int) bool { return history[i].Version < history[j].Version })
 	m.eventsByID[aggregateID] = history
 
+	for _, record := range records {
+		m.log = append(m.log, StreamRecord{
+			Record:      record,
+			AggregateID: aggregateID,
+			Position:    int64(len(m.log) + 1),
+		})
+	}
+
+	if len(records) > 0 {
+		close(m.changed)
+		m.changed = make(chan struct{})
+	}
+
 	return nil
 }
 
-func (m *memoryStore) Fetch(ctx context.Context, aggregateID string, version int) (History, error) {
+// Fetch implements the Store interface
+func (m *MemoryStore) Fetch(ctx context.Context, aggregateID string, version int) (History, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	history, ok := m.eventsByID[aggregateID]
+	if !ok {
+		return nil, NewError(nil, AggregateNotFound, "no aggregate found with id, %v", aggregateID)
+	}
+
+	if version > 0 {
+		history = history[:sort.Search(len(history), func(i int) bool { return history[i].Version > version })]
+	}
+
+	fetched := make(History, len(history))
+	copy(fetched, history)
+
+	return fetched, nil
+}
+
+// FetchAfter implements the AfterFetcher interface
+func (m *MemoryStore) FetchAfter(ctx context.Context, aggregateID string, version int) (History, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
 	history, ok := m.eventsByID[aggregateID]
 	if !ok {
 		return nil, NewError(nil, AggregateNotFound, "no aggregate found with id, %v", aggregateID)
 	}
 
-	return history, nil
+	index := sort.Search(len(history), func(i int) bool { return history[i].Version > version })
+	after := make(History, len(history)-index)
+	copy(after, history[index:])
+
+	return after, nil
+}
+
+// Read implements the Reader interface; positions in the MemoryStore are contiguous and start from 1
+func (m *MemoryStore) Read(ctx context.Context, startingPosition int64, recordCount int) ([]StreamRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if startingPosition < 0 {
+		startingPosition = 0
+	}
+
+	begin := int(startingPosition)
+	if begin >= len(m.log) || recordCount <= 0 {
+		return []StreamRecord{}, nil
+	}
+
+	end := len(m.log)
+	if begin+recordCount < end {
+		end = begin + recordCount
+	}
+
+	records := make([]StreamRecord, end-begin)
+	copy(records, m.log[begin:end])
+
+	return records, nil
+}
+
+// ReadTypes implements the TypeReader interface
+func (m *MemoryStore) ReadTypes(ctx context.Context, startingPosition int64, recordCount int, eventTypes ...string) ([]StreamRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if startingPosition < 0 {
+		startingPosition = 0
+	}
+
+	records := []StreamRecord{}
+	for index := int(startingPosition); index < len(m.log) && len(records) < recordCount; index++ {
+		record := m.log[index]
+		for _, eventType := range eventTypes {
+			if record.Type == eventType {
+				records = append(records, record)
+				break
+			}
+		}
+	}
+
+	return records, nil
+}
+
+// Subscribe implements the Subscriber interface; records are delivered in position order.  A slow reader does not
+// block Save; records are delivered from the log as the reader is ready for them.
+func (m *MemoryStore) Subscribe(ctx context.Context, filters ...Filter) <-chan StreamRecord {
+	m.mux.Lock()
+	position := len(m.log)
+	m.mux.Unlock()
+
+	ch := make(chan StreamRecord)
+	go func() {
+		defer close(ch)
+
+		for {
+			m.mux.Lock()
+			records := make([]StreamRecord, len(m.log)-position)
+			copy(records, m.log[position:])
+			changed := m.changed
+			m.mux.Unlock()
+
+			for _, record := range records {
+				position++
+				if !Match(record, filters...) {
+					continue
+				}
+
+				select {
+				case ch <- record:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if len(records) > 0 {
+				continue
+			}
+
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
 }
@@ -0,0 +1,93 @@
+package eventsource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore_Read(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	r1 := Record{Version: 1, Data: []byte("a")}
+	r2 := Record{Version: 2, Data: []byte("b")}
+	r3 := Record{Version: 1, Data: []byte("c")}
+
+	assert.Nil(t, store.Save(ctx, "abc", r1, r2))
+	assert.Nil(t, store.Save(ctx, "def", r3))
+
+	records, err := store.Read(ctx, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []StreamRecord{
+		{Record: r1, AggregateID: "abc", Position: 1},
+		{Record: r2, AggregateID: "abc", Position: 2},
+		{Record: r3, AggregateID: "def", Position: 3},
+	}, records)
+
+	records, err = store.Read(ctx, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []StreamRecord{{Record: r2, AggregateID: "abc", Position: 2}}, records)
+
+	records, err = store.Read(ctx, 3, 10)
+	assert.Nil(t, err)
+	assert.Len(t, records, 0)
+}
+
+func TestMemoryStore_FetchAfter(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	r1 := Record{Version: 1, Data: []byte("a")}
+	r2 := Record{Version: 2, Data: []byte("b")}
+	r3 := Record{Version: 3, Data: []byte("c")}
+	assert.Nil(t, store.Save(ctx, "abc", r1, r2, r3))
+
+	history, err := store.FetchAfter(ctx, "abc", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, History{r2, r3}, history)
+
+	history, err = store.FetchAfter(ctx, "abc", 3)
+	assert.Nil(t, err)
+	assert.Len(t, history, 0)
+}
+
+func TestMemoryStore_ReadTypes(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	r1 := Record{Version: 1, Type: "Created", Data: []byte("a")}
+	r2 := Record{Version: 2, Type: "NameSet", Data: []byte("b")}
+	r3 := Record{Version: 1, Type: "Created", Data: []byte("c")}
+
+	assert.Nil(t, store.Save(ctx, "abc", r1, r2))
+	assert.Nil(t, store.Save(ctx, "def", r3))
+
+	records, err := store.ReadTypes(ctx, 0, 10, "Created")
+	assert.Nil(t, err)
+	assert.Equal(t, []StreamRecord{
+		{Record: r1, AggregateID: "abc", Position: 1},
+		{Record: r3, AggregateID: "def", Position: 3},
+	}, records)
+
+	records, err = store.ReadTypes(ctx, 1, 1, "Created", "NameSet")
+	assert.Nil(t, err)
+	assert.Equal(t, []StreamRecord{{Record: r2, AggregateID: "abc", Position: 2}}, records)
+}
+
+func TestMemoryStore_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	store := NewMemoryStore()
+	assert.Nil(t, store.Save(ctx, "abc", Record{Version: 1, Type: "Created"}))
+	cancel()
+
+	_, err := store.FetchAfter(ctx, "abc", 0)
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = store.Read(ctx, 0, 10)
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = store.ReadTypes(ctx, 0, 10, "Created")
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,383 @@
+// Package storetest provides a behavioral test suite for implementations of eventsource.Store
+package storetest
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/savaki/eventsource"
+)
+
+// Factory returns the Store under test.  The suite uses unique aggregate ids, so the same underlying storage may be
+// shared across tests.
+type Factory func(t *testing.T) eventsource.Store
+
+var (
+	counter int64
+)
+
+// newAggregateID returns an aggregate id unique to this process
+func newAggregateID() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatInt(atomic.AddInt64(&counter, 1), 36)
+}
+
+// makeRecords returns records with the specified versions
+func makeRecords(versions ...int) []eventsource.Record {
+	records := make([]eventsource.Record, 0, len(versions))
+	for _, version := range versions {
+		records = append(records, eventsource.Record{
+			Version: version,
+			At:      eventsource.EpochMillis(version * 1000),
+			Type:    "Type" + strconv.Itoa(version),
+			Data:    []byte("data-" + strconv.Itoa(version)),
+		})
+	}
+	return records
+}
+
+// TestStore runs the suite against the Store returned by the factory.  Optional interfaces, such as
+// eventsource.VersionSaver and eventsource.Reader, are tested when the Store implements them.
+func TestStore(t *testing.T, factory Factory) {
+	tests := map[string]func(t *testing.T, store eventsource.Store){
+		"save and fetch":          testSaveAndFetch,
+		"ordering":                testOrdering,
+		"version bounded fetch":   testVersionBoundedFetch,
+		"not found":               testNotFound,
+		"duplicate version":       testDuplicateVersion,
+		"duplicate within save":   testDuplicateWithinSave,
+		"concurrent saves":        testConcurrentSaves,
+		"concurrent same version": testConcurrentSameVersion,
+		"canceled context":        testCanceledContext,
+		"save version":            testSaveVersion,
+		"fetch after":             testFetchAfter,
+		"read":                    testRead,
+	}
+
+	for label, fn := range tests {
+		fn := fn
+		t.Run(label, func(t *testing.T) {
+			fn(t, factory(t))
+		})
+	}
+}
+
+func testSaveAndFetch(t *testing.T, store eventsource.Store) {
+	ctx := context.Background()
+	aggregateID := newAggregateID()
+	records := makeRecords(1, 2, 3)
+
+	if err := store.Save(ctx, aggregateID, records...); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	history, err := store.Fetch(ctx, aggregateID, 0)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	assertHistory(t, records, history)
+
+	more := makeRecords(4)
+	if err := store.Save(ctx, aggregateID, more...); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	history, err = store.Fetch(ctx, aggregateID, 0)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	assertHistory(t, append(records, more...), history)
+}
+
+func testOrdering(t *testing.T, store eventsource.Store) {
+	ctx := context.Background()
+	aggregateID := newAggregateID()
+
+	if err := store.Save(ctx, aggregateID, makeRecords(3, 1, 2)...); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	history, err := store.Fetch(ctx, aggregateID, 0)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	assertHistory(t, makeRecords(1, 2, 3), history)
+}
+
+func testVersionBoundedFetch(t *testing.T, store eventsource.Store) {
+	ctx := context.Background()
+	aggregateID := newAggregateID()
+
+	if err := store.Save(ctx, aggregateID, makeRecords(1, 2, 3, 4, 5)...); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	for version := 1; version <= 5; version++ {
+		history, err := store.Fetch(ctx, aggregateID, version)
+		if err != nil {
+			t.Fatalf("Fetch(%v) failed: %v", version, err)
+		}
+
+		expected := makeRecords()
+		for v := 1; v <= version; v++ {
+			expected = append(expected, makeRecords(v)...)
+		}
+		assertHistory(t, expected, history)
+	}
+}
+
+func testNotFound(t *testing.T, store eventsource.Store) {
+	_, err := store.Fetch(context.Background(), newAggregateID(), 0)
+	assertCode(t, eventsource.AggregateNotFound, err)
+}
+
+func testDuplicateVersion(t *testing.T, store eventsource.Store) {
+	ctx := context.Background()
+	aggregateID := newAggregateID()
+
+	if err := store.Save(ctx, aggregateID, makeRecords(1, 2)...); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	duplicate := makeRecords(2)
+	duplicate[0].At++
+	duplicate[0].Data = []byte("duplicate")
+
+	err := store.Save(ctx, aggregateID, duplicate...)
+	assertCode(t, eventsource.DuplicateVersion, err)
+
+	history, err := store.Fetch(ctx, aggregateID, 0)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	assertHistory(t, makeRecords(1, 2), history)
+}
+
+func testDuplicateWithinSave(t *testing.T, store eventsource.Store) {
+	ctx := context.Background()
+	aggregateID := newAggregateID()
+
+	err := store.Save(ctx, aggregateID, makeRecords(1, 1)...)
+	assertCode(t, eventsource.DuplicateVersion, err)
+}
+
+func testConcurrentSaves(t *testing.T, store eventsource.Store) {
+	ctx := context.Background()
+	writers := 8
+	versions := 5
+
+	aggregateIDs := make([]string, 0, writers)
+	for i := 0; i < writers; i++ {
+		aggregateIDs = append(aggregateIDs, newAggregateID())
+	}
+
+	wg := &sync.WaitGroup{}
+	errs := make(chan error, writers*versions)
+	for _, aggregateID := range aggregateIDs {
+		wg.Add(1)
+		go func(aggregateID string) {
+			defer wg.Done()
+			for version := 1; version <= versions; version++ {
+				errs <- store.Save(ctx, aggregateID, makeRecords(version)...)
+			}
+		}(aggregateID)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent Save failed: %v", err)
+		}
+	}
+
+	expected := makeRecords()
+	for version := 1; version <= versions; version++ {
+		expected = append(expected, makeRecords(version)...)
+	}
+
+	for _, aggregateID := range aggregateIDs {
+		history, err := store.Fetch(ctx, aggregateID, 0)
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		assertHistory(t, expected, history)
+	}
+}
+
+func testConcurrentSameVersion(t *testing.T, store eventsource.Store) {
+	ctx := context.Background()
+	aggregateID := newAggregateID()
+	writers := 8
+
+	wg := &sync.WaitGroup{}
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- store.Save(ctx, aggregateID, makeRecords(1)...)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assertCode(t, eventsource.DuplicateVersion, err)
+	}
+
+	if succeeded != 1 {
+		t.Fatalf("expected exactly 1 of %v concurrent saves of the same version to succeed; got %v", writers, succeeded)
+	}
+}
+
+func testCanceledContext(t *testing.T, store eventsource.Store) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	aggregateID := newAggregateID()
+	if err := store.Save(ctx, aggregateID, makeRecords(1)...); err == nil {
+		t.Fatalf("expected Save with a canceled context to fail")
+	}
+
+	if _, err := store.Fetch(ctx, aggregateID, 0); err == nil {
+		t.Fatalf("expected Fetch with a canceled context to fail")
+	}
+}
+
+func testSaveVersion(t *testing.T, store eventsource.Store) {
+	saver, ok := store.(eventsource.VersionSaver)
+	if !ok {
+		t.Skip("store does not implement eventsource.VersionSaver")
+	}
+
+	ctx := context.Background()
+	aggregateID := newAggregateID()
+
+	if err := saver.SaveVersion(ctx, aggregateID, 0, makeRecords(1, 2)...); err != nil {
+		t.Fatalf("SaveVersion failed: %v", err)
+	}
+
+	// a writer that loaded version 1 conflicts with version 2
+	err := saver.SaveVersion(ctx, aggregateID, 1, makeRecords(2)...)
+	assertCode(t, eventsource.DuplicateVersion, err)
+
+	if err := saver.SaveVersion(ctx, aggregateID, 2, makeRecords(3)...); err != nil {
+		t.Fatalf("SaveVersion failed: %v", err)
+	}
+
+	history, err := store.Fetch(ctx, aggregateID, 0)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	assertHistory(t, makeRecords(1, 2, 3), history)
+}
+
+func testFetchAfter(t *testing.T, store eventsource.Store) {
+	fetcher, ok := store.(eventsource.AfterFetcher)
+	if !ok {
+		t.Skip("store does not implement eventsource.AfterFetcher")
+	}
+
+	ctx := context.Background()
+	aggregateID := newAggregateID()
+
+	if err := store.Save(ctx, aggregateID, makeRecords(1, 2, 3, 4)...); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	history, err := fetcher.FetchAfter(ctx, aggregateID, 2)
+	if err != nil {
+		t.Fatalf("FetchAfter failed: %v", err)
+	}
+	assertHistory(t, makeRecords(3, 4), history)
+
+	history, err = fetcher.FetchAfter(ctx, aggregateID, 4)
+	if err != nil {
+		t.Fatalf("FetchAfter failed: %v", err)
+	}
+	assertHistory(t, makeRecords(), history)
+}
+
+func testRead(t *testing.T, store eventsource.Store) {
+	reader, ok := store.(eventsource.Reader)
+	if !ok {
+		t.Skip("store does not implement eventsource.Reader")
+	}
+
+	ctx := context.Background()
+	aggregateID := newAggregateID()
+	records := makeRecords(1, 2, 3)
+
+	if err := store.Save(ctx, aggregateID, records...); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	var position int64
+	found := makeRecords()
+	for {
+		page, err := reader.Read(ctx, position, 2)
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		if len(page) > 2 {
+			t.Fatalf("Read returned %v records; expected no more than 2", len(page))
+		}
+
+		for _, record := range page {
+			if record.Position <= position {
+				t.Fatalf("Read returned position %v after position %v", record.Position, position)
+			}
+			position = record.Position
+
+			if record.AggregateID == aggregateID {
+				found = append(found, record.Record)
+			}
+		}
+	}
+
+	assertHistory(t, records, found)
+}
+
+func assertHistory(t *testing.T, expected []eventsource.Record, history eventsource.History) {
+	t.Helper()
+
+	if len(expected) != len(history) {
+		t.Fatalf("expected %v records; got %v", len(expected), len(history))
+	}
+
+	for index, want := range expected {
+		got := history[index]
+		if want.Version != got.Version || want.At != got.At || want.Type != got.Type || string(want.Data) != string(got.Data) {
+			t.Fatalf("record %v: expected %#v; got %#v", index, want, got)
+		}
+	}
+}
+
+func assertCode(t *testing.T, code string, err error) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("expected error with code %v; got nil", code)
+	}
+
+	v, ok := err.(eventsource.Error)
+	if !ok {
+		t.Fatalf("expected eventsource.Error with code %v; got %#v", code, err)
+	}
+
+	if v.Code() != code {
+		t.Fatalf("expected error with code %v; got %v", code, v.Code())
+	}
+}
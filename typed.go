@@ -0,0 +1,88 @@
+package eventsource
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// TypedRepository provides a type safe Repository for aggregates of type T, removing the need to type assert the
+// results of Load.  T must be a pointer to the aggregate struct e.g. *User
+type TypedRepository[T Aggregate] struct {
+	repository Repository
+}
+
+// NewTyped returns a TypedRepository for aggregates of type T using the same Options as New e.g.
+//
+//	repo := eventsource.NewTyped[*User](eventsource.WithStore(store))
+//
+// NewTyped panics if T is not a pointer to a struct as New would be unable to create instances of T
+func NewTyped[T Aggregate](opts ...Option) *TypedRepository[T] {
+	if t := reflect.TypeOf((*T)(nil)).Elem(); t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("eventsource: NewTyped requires a pointer to a struct, got %v", t))
+	}
+
+	var prototype T
+	return &TypedRepository[T]{
+		repository: New(prototype, opts...),
+	}
+}
+
+// Repository returns the underlying Repository
+func (r *TypedRepository[T]) Repository() Repository {
+	return r.repository
+}
+
+// Bind binds the events to the underlying Repository; see Repository
+func (r *TypedRepository[T]) Bind(events ...Event) error {
+	return r.repository.Bind(events...)
+}
+
+// New returns a new instance of the aggregate
+func (r *TypedRepository[T]) New() T {
+	return r.repository.New().(T)
+}
+
+// Save saves the events; see Repository
+func (r *TypedRepository[T]) Save(ctx context.Context, events ...Event) error {
+	return r.repository.Save(ctx, events...)
+}
+
+// SaveVersion saves the events provided the aggregate is still at expectedVersion; see Repository
+func (r *TypedRepository[T]) SaveVersion(ctx context.Context, expectedVersion int, events ...Event) error {
+	return r.repository.SaveVersion(ctx, expectedVersion, events...)
+}
+
+// Load loads the most recent version of the aggregate
+func (r *TypedRepository[T]) Load(ctx context.Context, aggregateID string) (T, error) {
+	return typed[T](r.repository.Load(ctx, aggregateID))
+}
+
+// LoadWithVersion loads the most recent version of the aggregate along with its version; see Repository
+func (r *TypedRepository[T]) LoadWithVersion(ctx context.Context, aggregateID string) (T, int, error) {
+	aggregate, version, err := r.repository.LoadWithVersion(ctx, aggregateID)
+	if err != nil {
+		var zero T
+		return zero, 0, err
+	}
+	return aggregate.(T), version, nil
+}
+
+// LoadVersion loads the aggregate as of the version provided; see Repository
+func (r *TypedRepository[T]) LoadVersion(ctx context.Context, aggregateID string, version int) (T, error) {
+	return typed[T](r.repository.LoadVersion(ctx, aggregateID, version))
+}
+
+// LoadAt loads the aggregate as of the time provided; see Repository
+func (r *TypedRepository[T]) LoadAt(ctx context.Context, aggregateID string, t time.Time) (T, error) {
+	return typed[T](r.repository.LoadAt(ctx, aggregateID, t))
+}
+
+func typed[T Aggregate](aggregate Aggregate, err error) (T, error) {
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return aggregate.(T), nil
+}
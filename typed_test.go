@@ -0,0 +1,56 @@
+package eventsource_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/savaki/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedRepository(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	repo := eventsource.NewTyped[*Entity]()
+	assert.Equal(t, &Entity{}, repo.New())
+
+	err := repo.Bind(EntityCreated{}, EntityNameSet{})
+	assert.Nil(t, err)
+
+	err = repo.Save(ctx,
+		&EntityCreated{Model: eventsource.Model{ID: id, Version: 1, At: time.Unix(10, 0)}},
+		&EntityNameSet{Model: eventsource.Model{ID: id, Version: 2, At: time.Unix(20, 0)}, Name: "Jones"},
+	)
+	assert.Nil(t, err)
+
+	entity, err := repo.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Jones", entity.Name)
+
+	entity, err = repo.LoadVersion(ctx, id, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "", entity.Name)
+
+	entity, err = repo.LoadAt(ctx, id, time.Unix(20, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, entity.Version)
+
+	entity, err = repo.Load(ctx, "unknown")
+	assert.Nil(t, entity)
+	assert.True(t, errors.Is(err, eventsource.ErrAggregateNotFound))
+}
+
+type ValueAggregate struct{}
+
+func (ValueAggregate) On(event eventsource.Event) bool {
+	return false
+}
+
+func TestNewTyped_InvalidType(t *testing.T) {
+	assert.Panics(t, func() { eventsource.NewTyped[ValueAggregate]() })
+	assert.Panics(t, func() { eventsource.NewTyped[eventsource.Aggregate]() })
+	assert.NotPanics(t, func() { eventsource.NewTyped[*ValueAggregate]() })
+}
@@ -0,0 +1,110 @@
+package eventsource
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// TypeRegistry maps event type names, as returned by EventType, to the Go types of the events.  Each name may be
+// bound to a single Go type; aliases allow events saved under a former name to be loaded into the current type.
+type TypeRegistry struct {
+	mux     *sync.Mutex
+	types   map[string]reflect.Type
+	aliases map[string]string
+}
+
+// NewTypeRegistry returns a new, empty TypeRegistry
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		mux:     &sync.Mutex{},
+		types:   map[string]reflect.Type{},
+		aliases: map[string]string{},
+	}
+}
+
+// Register binds the event type of each event to its Go type.  Registering the same event again has no effect, while
+// registering an event type already bound to a different Go type, or used as an alias, returns an Error with the
+// DuplicateType code.
+func (r *TypeRegistry) Register(events ...Event) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for _, event := range events {
+		eventType, t := EventType(event)
+
+		if existing, ok := r.types[eventType]; ok && existing != t {
+			return NewError(nil, DuplicateType, "event type, %v, already bound to %v; unable to bind %v", eventType, existing, t)
+		}
+		if target, ok := r.aliases[eventType]; ok {
+			return NewError(nil, DuplicateType, "event type, %v, already an alias of %v", eventType, target)
+		}
+
+		r.types[eventType] = t
+	}
+
+	return nil
+}
+
+// Alias binds an additional event type name to the Go type of event, which is registered if it has not been already.
+// Events are always saved under their current event type; aliases are used only when looking up saved events.
+func (r *TypeRegistry) Alias(alias string, event Event) error {
+	if err := r.Register(event); err != nil {
+		return err
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	eventType, _ := EventType(event)
+
+	if _, ok := r.types[alias]; ok {
+		return NewError(nil, DuplicateType, "unable to alias %v to %v; %v is already an event type", alias, eventType, alias)
+	}
+	if target, ok := r.aliases[alias]; ok && target != eventType {
+		return NewError(nil, DuplicateType, "unable to alias %v to %v; already an alias of %v", alias, eventType, target)
+	}
+
+	r.aliases[alias] = eventType
+	return nil
+}
+
+// Lookup returns the Go type bound to the event type or alias provided
+func (r *TypeRegistry) Lookup(eventType string) (reflect.Type, bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if target, ok := r.aliases[eventType]; ok {
+		eventType = target
+	}
+
+	t, ok := r.types[eventType]
+	return t, ok
+}
+
+// Types returns the registered event types, excluding aliases, in sorted order
+func (r *TypeRegistry) Types() []string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	types := make([]string, 0, len(r.types))
+	for eventType := range r.types {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+
+	return types
+}
+
+// Aliases returns a copy of the registered aliases, keyed by alias, with the event type each refers to
+func (r *TypeRegistry) Aliases() map[string]string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	aliases := make(map[string]string, len(r.aliases))
+	for alias, eventType := range r.aliases {
+		aliases[alias] = eventType
+	}
+
+	return aliases
+}
@@ -0,0 +1,104 @@
+package eventsource_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/savaki/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+// EntityNameSetV1 is a distinct Go type that claims the event type of EntityNameSet
+type EntityNameSetV1 struct {
+	eventsource.Model
+	Name string
+}
+
+func (EntityNameSetV1) EventType() string { return "EntityNameSet" }
+
+func TestTypeRegistry(t *testing.T) {
+	types := eventsource.NewTypeRegistry()
+
+	err := types.Register(EntityCreated{}, EntityNameSet{})
+	assert.Nil(t, err)
+
+	// Test - Registering the same type again is permitted
+
+	err = types.Register(&EntityNameSet{})
+	assert.Nil(t, err)
+
+	// Test - Registering a different type under the same name fails
+
+	err = types.Register(EntityNameSetV1{})
+	assert.NotNil(t, err)
+
+	v, ok := err.(eventsource.Error)
+	assert.True(t, ok)
+	assert.Equal(t, eventsource.DuplicateType, v.Code())
+
+	// Test - Aliases resolve to the type they refer to
+
+	err = types.Alias("EntityRenamed", EntityNameSet{})
+	assert.Nil(t, err)
+
+	typ, ok := types.Lookup("EntityRenamed")
+	assert.True(t, ok)
+	assert.Equal(t, "EntityNameSet", typ.Name())
+
+	_, ok = types.Lookup("Unknown")
+	assert.False(t, ok)
+
+	// Test - Aliases may not clash with event types or other aliases
+
+	err = types.Alias("EntityCreated", EntityNameSet{})
+	assert.NotNil(t, err)
+
+	err = types.Alias("EntityRenamed", EntityCreated{})
+	assert.NotNil(t, err)
+
+	err = types.Register(EntityRenamed{})
+	assert.NotNil(t, err)
+
+	assert.Equal(t, []string{"EntityCreated", "EntityNameSet"}, types.Types())
+	assert.Equal(t, map[string]string{"EntityRenamed": "EntityNameSet"}, types.Aliases())
+}
+
+func TestRepositoryDuplicateType(t *testing.T) {
+	registry := eventsource.New(&Entity{})
+
+	err := registry.Bind(EntityNameSet{})
+	assert.Nil(t, err)
+
+	err = registry.Bind(EntityNameSetV1{})
+	assert.NotNil(t, err)
+
+	v, ok := err.(eventsource.Error)
+	assert.True(t, ok)
+	assert.Equal(t, eventsource.DuplicateType, v.Code())
+}
+
+func TestRepositoryAlias(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	store := eventsource.NewMemoryStore()
+	err := store.Save(ctx, id, eventsource.Record{
+		Version: 1,
+		Data:    []byte(`{"t":"EntityNameChanged","d":{"ID":"123","Version":1,"Name":"Joe"}}`),
+	})
+	assert.Nil(t, err)
+
+	types := eventsource.NewTypeRegistry()
+	err = types.Alias("EntityNameChanged", EntityNameSet{})
+	assert.Nil(t, err)
+
+	registry := eventsource.New(&Entity{},
+		eventsource.WithStore(store),
+		eventsource.WithTypeRegistry(types),
+	)
+	registry.Bind(EntityNameSet{})
+
+	v, err := registry.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Joe", v.(*Entity).Name)
+}
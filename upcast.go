@@ -0,0 +1,71 @@
+package eventsource
+
+import "sync"
+
+// SchemaVersioner is an optional interface that can be applied to an Event to specify the version of its schema.
+// Events that do not implement SchemaVersioner are schema version 0.
+type SchemaVersioner interface {
+	// SchemaVersion returns the current schema version of the event
+	SchemaVersion() int
+}
+
+// UpcastFunc rewrites the raw payload of an event from one schema version to the next.  The event type returned
+// may differ from the one provided to accommodate events that have been renamed.
+type UpcastFunc func(eventType string, data []byte) (string, []byte, error)
+
+type upcastKey struct {
+	eventType string
+	version   int
+}
+
+// Upcaster holds the chain of UpcastFuncs used by a Serializer to bring previously stored events up to the
+// schema of the currently bound event types
+type Upcaster struct {
+	mux     *sync.RWMutex
+	upcasts map[upcastKey]UpcastFunc
+}
+
+// NewUpcaster returns a new, empty Upcaster
+func NewUpcaster() *Upcaster {
+	return &Upcaster{
+		mux:     &sync.RWMutex{},
+		upcasts: map[upcastKey]UpcastFunc{},
+	}
+}
+
+// Register registers fn to upcast events of eventType from schema version to version+1.  Returns an Error with the
+// DuplicateType code if an UpcastFunc has already been registered for the event type and version.
+func (u *Upcaster) Register(eventType string, version int, fn UpcastFunc) error {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	key := upcastKey{eventType: eventType, version: version}
+	if _, ok := u.upcasts[key]; ok {
+		return NewError(nil, DuplicateType, "upcast already registered for event type, %v, version %v", eventType, version)
+	}
+
+	u.upcasts[key] = fn
+	return nil
+}
+
+// Upcast applies the registered UpcastFuncs, in version order, until no UpcastFunc is registered for the resulting
+// event type and version.  Returns the final event type, schema version, and data.
+func (u *Upcaster) Upcast(eventType string, version int, data []byte) (string, int, []byte, error) {
+	u.mux.RLock()
+	defer u.mux.RUnlock()
+
+	for {
+		fn, ok := u.upcasts[upcastKey{eventType: eventType, version: version}]
+		if !ok {
+			return eventType, version, data, nil
+		}
+
+		upcastType, upcastData, err := fn(eventType, data)
+		if err != nil {
+			return "", 0, nil, NewError(err, InvalidEncoding, "unable to upcast event type, %v, from version %v", eventType, version)
+		}
+
+		eventType, data = upcastType, upcastData
+		version++
+	}
+}
@@ -0,0 +1,101 @@
+package eventsource_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/savaki/eventsource"
+	"github.com/stretchr/testify/assert"
+)
+
+// EntityRenamed is version 1 of the event previously stored as EntityNameChanged{Name}
+type EntityRenamed struct {
+	eventsource.Model
+	FullName string
+}
+
+func (EntityRenamed) SchemaVersion() int {
+	return 1
+}
+
+type Named struct {
+	FullName string
+}
+
+func (n *Named) On(event eventsource.Event) bool {
+	switch v := event.(type) {
+	case *EntityRenamed:
+		n.FullName = v.FullName
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+func newUpcaster(t *testing.T) *eventsource.Upcaster {
+	upcaster := eventsource.NewUpcaster()
+	err := upcaster.Register("EntityNameChanged", 0, func(eventType string, data []byte) (string, []byte, error) {
+		return "EntityRenamed", bytes.Replace(data, []byte(`"Name"`), []byte(`"FullName"`), 1), nil
+	})
+	assert.Nil(t, err)
+	return upcaster
+}
+
+func TestUpcaster(t *testing.T) {
+	upcaster := newUpcaster(t)
+
+	eventType, version, data, err := upcaster.Upcast("EntityNameChanged", 0, []byte(`{"Name":"Joe"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "EntityRenamed", eventType)
+	assert.Equal(t, 1, version)
+	assert.Equal(t, `{"FullName":"Joe"}`, string(data))
+
+	// Test - Events already at the current version are left untouched
+
+	eventType, version, data, err = upcaster.Upcast("EntityRenamed", 1, []byte(`{"FullName":"Joe"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "EntityRenamed", eventType)
+	assert.Equal(t, 1, version)
+	assert.Equal(t, `{"FullName":"Joe"}`, string(data))
+
+	// Test - Registering the same event type and version twice fails
+
+	err = upcaster.Register("EntityNameChanged", 0, nil)
+	assert.NotNil(t, err)
+}
+
+func TestJSONSerializerUpcast(t *testing.T) {
+	ctx := context.Background()
+	id := "123"
+
+	store := eventsource.NewMemoryStore()
+	err := store.Save(ctx, id, eventsource.Record{
+		Version: 1,
+		Data:    []byte(`{"t":"EntityNameChanged","d":{"ID":"123","Version":1,"Name":"Joe"}}`),
+	})
+	assert.Nil(t, err)
+
+	serializer := eventsource.JSONSerializer(eventsource.WithUpcaster(newUpcaster(t)))
+	registry := eventsource.New(&Named{},
+		eventsource.WithStore(store),
+		eventsource.WithSerializer(serializer),
+	)
+	registry.Bind(EntityRenamed{})
+
+	v, err := registry.Load(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Joe", v.(*Named).FullName)
+
+	// Test - New events are written with their current schema version and are not upcast
+
+	record, err := serializer.Serialize(EntityRenamed{Model: eventsource.Model{ID: id, Version: 2}, FullName: "Jane"})
+	assert.Nil(t, err)
+	assert.Contains(t, string(record.Data), `"v":1`)
+
+	event, err := serializer.Deserialize(record)
+	assert.Nil(t, err)
+	assert.Equal(t, "Jane", event.(*EntityRenamed).FullName)
+}